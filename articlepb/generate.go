@@ -0,0 +1,5 @@
+package articlepb
+
+// Regenerate the messages and stubs after editing articles.proto. It needs
+// protoc with protoc-gen-go and protoc-gen-go-grpc on the PATH.
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative articles.proto
@@ -0,0 +1,24 @@
+package buildinfo
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	info := Get()
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+	assert.NotEmpty(t, info.Version)
+}
+
+func TestGetLinkerValues(t *testing.T) {
+	Version, Commit, BuildTime = "v1.2.3", "abc123", "2025-01-01T00:00:00Z"
+	defer func() { Version, Commit, BuildTime = "", "", "" }()
+
+	info := Get()
+	assert.Equal(t, "v1.2.3", info.Version)
+	assert.Equal(t, "abc123", info.Commit)
+	assert.Equal(t, "2025-01-01T00:00:00Z", info.BuildTime)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/brothergiez/restful-api/config"
+)
+
+const usage = `Usage: restful-api [-config FILE] [command]
+
+Without a command the API server is started.
+
+Commands:
+  config print    Print the effective configuration with secrets masked.
+`
+
+// runCommand runs a CLI command and returns the process exit code.
+func runCommand(cfg config.Config, args []string) int {
+	switch strings.Join(args, " ") {
+	case "config print":
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to print configuration: %v\n", err)
+			return 1
+		}
+		return 0
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+}
@@ -0,0 +1,347 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/handlers"
+	"github.com/brothergiez/restful-api/middlewares"
+	"github.com/brothergiez/restful-api/presence"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/brothergiez/restful-api/webhooks"
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the effective application configuration. Values are resolved in
+// order of increasing precedence: defaults, the optional config file, then
+// environment variables (including those loaded from .env).
+type Config struct {
+	Server      ServerConfig      `yaml:"server" toml:"server"`
+	Storage     StorageConfig     `yaml:"storage" toml:"storage"`
+	Articles    ArticlesConfig    `yaml:"articles" toml:"articles"`
+	Logging     LoggingConfig     `yaml:"logging" toml:"logging"`
+	Tracing     TracingConfig     `yaml:"tracing" toml:"tracing"`
+	RateLimit   RateLimitConfig   `yaml:"rateLimit" toml:"rateLimit"`
+	Auth        AuthConfig        `yaml:"auth" toml:"auth"`
+	CORS        CORSConfig        `yaml:"cors" toml:"cors"`
+	Compression CompressionConfig `yaml:"compression" toml:"compression"`
+	Idempotency IdempotencyConfig `yaml:"idempotency" toml:"idempotency"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks" toml:"webhooks"`
+	Presence    PresenceConfig    `yaml:"presence" toml:"presence"`
+	GraphQL     GraphQLConfig     `yaml:"graphql" toml:"graphql"`
+	GRPC        GRPCConfig        `yaml:"grpc" toml:"grpc"`
+}
+
+type ServerConfig struct {
+	Port              int       `yaml:"port" toml:"port" env:"APP_PORT"`
+	ReadTimeout       Duration  `yaml:"readTimeout" toml:"readTimeout" env:"SERVER_READ_TIMEOUT"`
+	ReadHeaderTimeout Duration  `yaml:"readHeaderTimeout" toml:"readHeaderTimeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	WriteTimeout      Duration  `yaml:"writeTimeout" toml:"writeTimeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       Duration  `yaml:"idleTimeout" toml:"idleTimeout" env:"SERVER_IDLE_TIMEOUT"`
+	ShutdownTimeout   Duration  `yaml:"shutdownTimeout" toml:"shutdownTimeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+	TLS               TLSConfig `yaml:"tls" toml:"tls"`
+	// H2C accepts HTTP/2 without TLS for internal traffic.
+	H2C bool `yaml:"h2c" toml:"h2c" env:"SERVER_H2C"`
+}
+
+type TLSConfig struct {
+	CertFile string `yaml:"certFile" toml:"certFile" env:"TLS_CERT_FILE"`
+	KeyFile  string `yaml:"keyFile" toml:"keyFile" env:"TLS_KEY_FILE"`
+	// ClientCAFile enables mutual TLS.
+	ClientCAFile string `yaml:"clientCAFile" toml:"clientCAFile" env:"TLS_CLIENT_CA_FILE"`
+	// ClientAuth is "require" or "verify_if_given".
+	ClientAuth     string   `yaml:"clientAuth" toml:"clientAuth" env:"TLS_CLIENT_AUTH"`
+	ReloadInterval Duration `yaml:"reloadInterval" toml:"reloadInterval" env:"TLS_RELOAD_INTERVAL"`
+}
+
+type StorageConfig struct {
+	// Driver selects the repository backend. Only "memory" is available.
+	Driver string `yaml:"driver" toml:"driver" env:"STORAGE_DRIVER"`
+	// DataDir enables the write-ahead log and snapshots. Empty keeps the
+	// articles in memory only.
+	DataDir string `yaml:"dataDir" toml:"dataDir" env:"STORAGE_DATA_DIR"`
+	// Fsync is "always", "interval" or "never".
+	Fsync            string   `yaml:"fsync" toml:"fsync" env:"STORAGE_FSYNC"`
+	FsyncInterval    Duration `yaml:"fsyncInterval" toml:"fsyncInterval" env:"STORAGE_FSYNC_INTERVAL"`
+	SnapshotInterval Duration `yaml:"snapshotInterval" toml:"snapshotInterval" env:"STORAGE_SNAPSHOT_INTERVAL"`
+}
+
+type ArticlesConfig struct {
+	// BulkMaxOperations limits the operations in one POST /articles/bulk.
+	BulkMaxOperations int `yaml:"bulkMaxOperations" toml:"bulkMaxOperations" env:"BULK_MAX_OPERATIONS"`
+	// StreamReplaySize is the number of recent changes kept for clients of
+	// GET /articles/stream that reconnect with Last-Event-ID.
+	StreamReplaySize int `yaml:"streamReplaySize" toml:"streamReplaySize" env:"STREAM_REPLAY_SIZE"`
+	// StreamHeartbeat is how often an idle stream sends a keep-alive comment.
+	StreamHeartbeat Duration `yaml:"streamHeartbeat" toml:"streamHeartbeat" env:"STREAM_HEARTBEAT_INTERVAL"`
+}
+
+type LoggingConfig struct {
+	Format string            `yaml:"format" toml:"format" env:"LOG_FORMAT"`
+	Level  string            `yaml:"level" toml:"level" env:"LOG_LEVEL"`
+	Output string            `yaml:"output" toml:"output" env:"LOG_OUTPUT"`
+	Redact RedactionConfig   `yaml:"redact" toml:"redact"`
+	Body   BodyLoggingConfig `yaml:"body" toml:"body"`
+}
+
+type RedactionConfig struct {
+	// Fields and Headers are added to the built-in lists.
+	Fields  []string `yaml:"fields" toml:"fields" env:"LOG_REDACT_FIELDS"`
+	Headers []string `yaml:"headers" toml:"headers" env:"LOG_REDACT_HEADERS"`
+	// Patterns names the built-in value patterns to apply.
+	Patterns []string `yaml:"patterns" toml:"patterns" env:"LOG_REDACT_PATTERNS"`
+	Regex    string   `yaml:"regex" toml:"regex" env:"LOG_REDACT_REGEX"`
+	Mode     string   `yaml:"mode" toml:"mode" env:"LOG_REDACT_MODE"`
+}
+
+type BodyLoggingConfig struct {
+	MaxSize      int      `yaml:"maxSize" toml:"maxSize" env:"LOG_BODY_MAX_SIZE"`
+	ContentTypes []string `yaml:"contentTypes" toml:"contentTypes" env:"LOG_BODY_CONTENT_TYPES"`
+	SkipRoutes   []string `yaml:"skipRoutes" toml:"skipRoutes" env:"LOG_BODY_SKIP_ROUTES"`
+	SampleRate   float64  `yaml:"sampleRate" toml:"sampleRate" env:"LOG_BODY_SAMPLE_RATE"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER"`
+	ServiceName string  `yaml:"serviceName" toml:"serviceName" env:"OTEL_SERVICE_NAME"`
+	SampleRatio float64 `yaml:"sampleRatio" toml:"sampleRatio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+type RateLimitConfig struct {
+	RPS   float64 `yaml:"rps" toml:"rps" env:"RATE_LIMIT_RPS"`
+	Burst int     `yaml:"burst" toml:"burst" env:"RATE_LIMIT_BURST"`
+	// Routes holds per-route limits as "METHOD /path=rate:burst".
+	Routes  []string `yaml:"routes" toml:"routes" env:"RATE_LIMIT_ROUTES"`
+	IdleTTL Duration `yaml:"idleTTL" toml:"idleTTL" env:"RATE_LIMIT_IDLE_TTL"`
+}
+
+type AuthConfig struct {
+	// APIKeys identify the clients that send one in the X-API-Key header.
+	// Requests without a matching key are still served.
+	APIKeys []string `yaml:"apiKeys" toml:"apiKeys" env:"AUTH_API_KEYS" secret:"true"`
+}
+
+type CORSConfig struct {
+	// AllowedOrigins enables CORS when set. Entries may contain one "*"
+	// wildcard, e.g. "https://*.example.com", and "*" allows any origin.
+	AllowedOrigins   []string `yaml:"allowedOrigins" toml:"allowedOrigins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string `yaml:"allowedMethods" toml:"allowedMethods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string `yaml:"allowedHeaders" toml:"allowedHeaders" env:"CORS_ALLOWED_HEADERS"`
+	ExposedHeaders   []string `yaml:"exposedHeaders" toml:"exposedHeaders" env:"CORS_EXPOSED_HEADERS"`
+	AllowCredentials bool     `yaml:"allowCredentials" toml:"allowCredentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           Duration `yaml:"maxAge" toml:"maxAge" env:"CORS_MAX_AGE"`
+}
+
+type CompressionConfig struct {
+	// Encodings are offered in order of preference. Empty disables compression.
+	Encodings    []string `yaml:"encodings" toml:"encodings" env:"COMPRESSION_ENCODINGS"`
+	MinSize      int      `yaml:"minSize" toml:"minSize" env:"COMPRESSION_MIN_SIZE"`
+	ContentTypes []string `yaml:"contentTypes" toml:"contentTypes" env:"COMPRESSION_CONTENT_TYPES"`
+}
+
+type IdempotencyConfig struct {
+	// TTL is how long an Idempotency-Key and its stored response are kept.
+	TTL         Duration `yaml:"ttl" toml:"ttl" env:"IDEMPOTENCY_TTL"`
+	MaxBodySize int      `yaml:"maxBodySize" toml:"maxBodySize" env:"IDEMPOTENCY_MAX_BODY_SIZE"`
+	// MaxEntries caps the keys held in memory; the completed keys closest to
+	// expiring are dropped first.
+	MaxEntries int `yaml:"maxEntries" toml:"maxEntries" env:"IDEMPOTENCY_MAX_ENTRIES"`
+}
+
+type WebhooksConfig struct {
+	Workers   int      `yaml:"workers" toml:"workers" env:"WEBHOOK_WORKERS"`
+	QueueSize int      `yaml:"queueSize" toml:"queueSize" env:"WEBHOOK_QUEUE_SIZE"`
+	Timeout   Duration `yaml:"timeout" toml:"timeout" env:"WEBHOOK_TIMEOUT"`
+	// MaxAttempts counts the first attempt. Retries back off exponentially
+	// from InitialBackoff up to MaxBackoff.
+	MaxAttempts     int      `yaml:"maxAttempts" toml:"maxAttempts" env:"WEBHOOK_MAX_ATTEMPTS"`
+	InitialBackoff  Duration `yaml:"initialBackoff" toml:"initialBackoff" env:"WEBHOOK_INITIAL_BACKOFF"`
+	MaxBackoff      Duration `yaml:"maxBackoff" toml:"maxBackoff" env:"WEBHOOK_MAX_BACKOFF"`
+	DeliveryLogSize int      `yaml:"deliveryLogSize" toml:"deliveryLogSize" env:"WEBHOOK_DELIVERY_LOG_SIZE"`
+	DeadLetterSize  int      `yaml:"deadLetterSize" toml:"deadLetterSize" env:"WEBHOOK_DEAD_LETTER_SIZE"`
+	// AllowPrivateTargets permits receivers on loopback, private and
+	// link-local addresses.
+	AllowPrivateTargets bool `yaml:"allowPrivateTargets" toml:"allowPrivateTargets" env:"WEBHOOK_ALLOW_PRIVATE_TARGETS"`
+}
+
+type PresenceConfig struct {
+	// PingInterval is how often WebSocket clients are pinged. A session that
+	// has not answered or sent anything for StaleAfter is ended.
+	PingInterval Duration `yaml:"pingInterval" toml:"pingInterval" env:"PRESENCE_PING_INTERVAL"`
+	StaleAfter   Duration `yaml:"staleAfter" toml:"staleAfter" env:"PRESENCE_STALE_AFTER"`
+	MaxSessions  int      `yaml:"maxSessions" toml:"maxSessions" env:"PRESENCE_MAX_SESSIONS"`
+}
+
+// GraphQLConfig limits the queries of /graphql. Every field costs one
+// towards MaxComplexity; fields below a list count once per item of its limit.
+type GraphQLConfig struct {
+	MaxDepth      int `yaml:"maxDepth" toml:"maxDepth" env:"GRAPHQL_MAX_DEPTH"`
+	MaxComplexity int `yaml:"maxComplexity" toml:"maxComplexity" env:"GRAPHQL_MAX_COMPLEXITY"`
+}
+
+type GRPCConfig struct {
+	// Port of the gRPC server, next to the HTTP one. 0, the default,
+	// disables it.
+	Port int `yaml:"port" toml:"port" env:"GRPC_PORT"`
+}
+
+func Default() Config {
+	cors := middlewares.DefaultCORSConfig()
+	compression := middlewares.DefaultCompressionConfig()
+	idempotency := middlewares.DefaultIdempotencyConfig()
+	webhookDefaults := webhooks.DefaultConfig()
+	presenceDefaults := presence.DefaultConfig()
+	graphQLDefaults := handlers.DefaultGraphQLConfig()
+	persistence := repositories.DefaultPersistenceConfig()
+
+	return Config{
+		Server: ServerConfig{
+			Port:              8080,
+			ReadTimeout:       Duration(15 * time.Second),
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			WriteTimeout:      Duration(30 * time.Second),
+			IdleTimeout:       Duration(60 * time.Second),
+			ShutdownTimeout:   Duration(20 * time.Second),
+			TLS: TLSConfig{
+				ClientAuth:     "require",
+				ReloadInterval: Duration(time.Minute),
+			},
+		},
+		Storage: StorageConfig{
+			Driver:           "memory",
+			Fsync:            string(persistence.Fsync),
+			FsyncInterval:    Duration(persistence.FsyncInterval),
+			SnapshotInterval: Duration(persistence.SnapshotInterval),
+		},
+		Articles: ArticlesConfig{
+			BulkMaxOperations: handlers.DefaultMaxBulkOperations,
+			StreamReplaySize:  events.DefaultStreamReplaySize,
+			StreamHeartbeat:   Duration(handlers.DefaultStreamHeartbeat),
+		},
+		Logging: LoggingConfig{
+			Format: "json",
+			Level:  "info",
+			Output: "stdout",
+			Redact: RedactionConfig{
+				Patterns: []string{"email", "card"},
+				Mode:     "full",
+			},
+			Body: BodyLoggingConfig{
+				MaxSize:      64 * 1024,
+				ContentTypes: []string{"application/json", "application/xml", "application/x-www-form-urlencoded", "text/"},
+				SampleRate:   1,
+			},
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "restful-api",
+			SampleRatio: 1,
+		},
+		RateLimit: RateLimitConfig{
+			IdleTTL: Duration(10 * time.Minute),
+		},
+		CORS: CORSConfig{
+			AllowedMethods: cors.AllowedMethods,
+			AllowedHeaders: cors.AllowedHeaders,
+			ExposedHeaders: cors.ExposedHeaders,
+			MaxAge:         Duration(cors.MaxAge),
+		},
+		Compression: CompressionConfig{
+			Encodings:    compression.Encodings,
+			MinSize:      compression.MinSize,
+			ContentTypes: compression.ContentTypes,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:         Duration(idempotency.TTL),
+			MaxBodySize: int(idempotency.MaxBodySize),
+			MaxEntries:  idempotency.MaxEntries,
+		},
+		Webhooks: WebhooksConfig{
+			Workers:         webhookDefaults.Workers,
+			QueueSize:       webhookDefaults.QueueSize,
+			Timeout:         Duration(webhookDefaults.Timeout),
+			MaxAttempts:     webhookDefaults.MaxAttempts,
+			InitialBackoff:  Duration(webhookDefaults.InitialBackoff),
+			MaxBackoff:      Duration(webhookDefaults.MaxBackoff),
+			DeliveryLogSize: webhookDefaults.DeliveryLogSize,
+			DeadLetterSize:  webhookDefaults.DeadLetterSize,
+		},
+		Presence: PresenceConfig{
+			PingInterval: Duration(presenceDefaults.PingInterval),
+			StaleAfter:   Duration(presenceDefaults.StaleAfter),
+			MaxSessions:  presenceDefaults.MaxSessions,
+		},
+		GraphQL: GraphQLConfig{
+			MaxDepth:      graphQLDefaults.MaxDepth,
+			MaxComplexity: graphQLDefaults.MaxComplexity,
+		},
+	}
+}
+
+// Load resolves the configuration from the defaults, the optional file at
+// path (YAML or TOML, chosen by extension), a .env file in the working
+// directory and the environment, then validates the result. When path is
+// empty the CONFIG_FILE variable is used instead.
+func Load(path string) (Config, error) {
+	config := Default()
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return config, fmt.Errorf("load .env: %w", err)
+	}
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+
+	if path != "" {
+		if err := loadFile(path, &config); err != nil {
+			return config, err
+		}
+	}
+
+	if err := applyEnv(&config, os.LookupEnv); err != nil {
+		return config, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+func loadFile(path string, config *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(config)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	case ".toml":
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(config)
+	default:
+		return fmt.Errorf("unsupported config file type %q: use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/brothergiez/restful-api/middlewares"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// isolateEnv unsets every variable Load reads, so the tests see only what
+// they set themselves. The variables are restored when the test ends.
+func isolateEnv(t *testing.T) {
+	names := []string{"CONFIG_FILE"}
+	walkEnv(reflect.ValueOf(&Config{}).Elem(), func(_ reflect.Value, name string) {
+		names = append(names, name)
+	})
+	for _, name := range names {
+		t.Setenv(name, "")
+		assert.NoError(t, os.Unsetenv(name))
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	isolateEnv(t)
+	config, err := Load("")
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), config)
+	assert.Equal(t, ":8080", config.ForServer().Addr)
+	assert.Zero(t, config.GRPC.Port, "gRPC is opt-in")
+}
+
+func TestLoadYAML(t *testing.T) {
+	isolateEnv(t)
+	path := writeFile(t, "config.yaml", `
+server:
+  port: 9000
+  writeTimeout: 45s
+logging:
+  level: debug
+  redact:
+    fields: [ssn]
+rateLimit:
+  routes:
+    - GET /articles/search=2:5
+`)
+
+	config, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 9000, config.Server.Port)
+	assert.Equal(t, Duration(45*time.Second), config.Server.WriteTimeout)
+	assert.Equal(t, Duration(15*time.Second), config.Server.ReadTimeout)
+	assert.Equal(t, "debug", config.Logging.Level)
+	assert.Contains(t, config.ForRedaction().Fields, "ssn")
+	assert.Equal(t, middlewares.RateLimitRule{Rate: 2, Burst: 5}, config.ForRateLimit().Routes["GET /articles/search"])
+}
+
+func TestLoadTOML(t *testing.T) {
+	isolateEnv(t)
+	path := writeFile(t, "config.toml", `
+[server]
+port = 9100
+idleTimeout = "2m"
+
+[tracing]
+exporter = "stdout"
+`)
+
+	config, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 9100, config.Server.Port)
+	assert.Equal(t, 2*time.Minute, config.ForServer().IdleTimeout)
+	assert.Equal(t, "stdout", config.ForTracing().Exporter)
+}
+
+func TestLoadUnknownField(t *testing.T) {
+	isolateEnv(t)
+	_, err := Load(writeFile(t, "config.yaml", "server:\n  prot: 9000\n"))
+	assert.ErrorContains(t, err, "prot")
+
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.json", "{}"))
+	_, err = Load("")
+	assert.ErrorContains(t, err, "unsupported config file type")
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	isolateEnv(t)
+	path := writeFile(t, "config.yaml", "server:\n  port: 9000\nauth:\n  apiKeys: [file-key-0123456789]\n")
+	t.Setenv("APP_PORT", "5000")
+	t.Setenv("SERVER_SHUTDOWN_TIMEOUT", "5s")
+	t.Setenv("AUTH_API_KEYS", "env-key-0123456789, other-key-0123456789")
+	t.Setenv("LOG_REDACT_PATTERNS", "")
+
+	config, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 5000, config.Server.Port)
+	assert.Equal(t, Duration(5*time.Second), config.Server.ShutdownTimeout)
+	assert.Equal(t, []string{"env-key-0123456789", "other-key-0123456789"}, config.Auth.APIKeys)
+	assert.Empty(t, config.ForRedaction().Patterns)
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	isolateEnv(t)
+	t.Setenv("APP_PORT", "http")
+	t.Setenv("SERVER_READ_TIMEOUT", "soon")
+
+	_, err := Load("")
+	assert.ErrorContains(t, err, "invalid APP_PORT")
+	assert.ErrorContains(t, err, "invalid SERVER_READ_TIMEOUT")
+}
+
+func TestValidate(t *testing.T) {
+	config := Default()
+	config.Server.Port = 70000
+	config.Storage.Driver = "postgres"
+	config.Logging.Level = "loud"
+	config.Logging.Redact.Patterns = []string{"phone"}
+	config.Tracing.SampleRatio = 2
+	config.RateLimit.Routes = []string{"/articles/search=2"}
+	config.Auth.APIKeys = []string{"short"}
+	config.Server.TLS.CertFile = "server.crt"
+	config.Server.H2C = true
+	config.CORS.AllowedOrigins = []string{"example.com"}
+	config.Compression.Encodings = []string{"deflate"}
+	config.Articles.BulkMaxOperations = 0
+	config.Articles.StreamHeartbeat = 0
+	config.Storage.Fsync = "sometimes"
+	config.Idempotency.TTL = 0
+	config.Idempotency.MaxEntries = 0
+	config.Webhooks.MaxBackoff = 0
+	config.Presence.StaleAfter = Duration(time.Second)
+	config.GraphQL.MaxComplexity = 0
+	config.GRPC.Port = -1
+
+	err := config.Validate()
+	assert.ErrorContains(t, err, "server.port")
+	assert.ErrorContains(t, err, "storage.driver")
+	assert.ErrorContains(t, err, "logging.level")
+	assert.ErrorContains(t, err, "unknown pattern \"phone\"")
+	assert.ErrorContains(t, err, "tracing.sampleRatio")
+	assert.ErrorContains(t, err, "rateLimit.routes")
+	assert.ErrorContains(t, err, "auth.apiKeys")
+	assert.ErrorContains(t, err, "server.tls.certFile and server.tls.keyFile must be set together")
+	assert.ErrorContains(t, err, "server.h2c cannot be combined with TLS")
+	assert.ErrorContains(t, err, "cors.allowedOrigins")
+	assert.ErrorContains(t, err, "compression.encodings")
+	assert.ErrorContains(t, err, "articles.bulkMaxOperations")
+	assert.ErrorContains(t, err, "articles.streamHeartbeat")
+	assert.ErrorContains(t, err, "storage.fsync")
+	assert.ErrorContains(t, err, "idempotency.ttl")
+	assert.ErrorContains(t, err, "idempotency.maxEntries")
+	assert.ErrorContains(t, err, "webhooks.initialBackoff")
+	assert.ErrorContains(t, err, "presence.pingInterval")
+	assert.ErrorContains(t, err, "graphql.maxComplexity")
+	assert.ErrorContains(t, err, "grpc.port must be between")
+
+	assert.NoError(t, Default().Validate())
+}
+
+func TestValidateCORSCredentials(t *testing.T) {
+	config := Default()
+	config.CORS.AllowedOrigins = []string{"*"}
+	config.CORS.AllowCredentials = true
+	assert.ErrorContains(t, config.Validate(), "cors.allowCredentials cannot be combined")
+
+	config.CORS.AllowedOrigins = []string{"https://app.example.com"}
+	assert.NoError(t, config.Validate())
+}
+
+func TestPrintMasksSecrets(t *testing.T) {
+	config := Default()
+	config.Auth.APIKeys = []string{"secret-key-0123456789"}
+
+	var output bytes.Buffer
+	assert.NoError(t, config.Print(&output))
+
+	assert.Contains(t, output.String(), "port: 8080")
+	assert.Contains(t, output.String(), "readTimeout: 15s")
+	assert.Contains(t, output.String(), "- '******'")
+	assert.NotContains(t, output.String(), "secret-key-0123456789")
+	assert.Equal(t, "secret-key-0123456789", config.Auth.APIKeys[0])
+}
+
+func TestForRateLimitDefaultBurst(t *testing.T) {
+	config := Default()
+	config.RateLimit.RPS = 2.5
+
+	rateLimit := config.ForRateLimit()
+	assert.Equal(t, middlewares.RateLimitRule{Rate: 2.5, Burst: 3}, rateLimit.Default)
+	assert.Equal(t, 10*time.Minute, rateLimit.IdleTTL)
+}
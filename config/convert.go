@@ -0,0 +1,169 @@
+package config
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brothergiez/restful-api/grpcapi"
+	"github.com/brothergiez/restful-api/handlers"
+	"github.com/brothergiez/restful-api/logger"
+	"github.com/brothergiez/restful-api/middlewares"
+	"github.com/brothergiez/restful-api/presence"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/brothergiez/restful-api/server"
+	"github.com/brothergiez/restful-api/tracing"
+	"github.com/brothergiez/restful-api/webhooks"
+)
+
+// The For* methods translate a validated Config into the option types of the
+// packages that consume it.
+
+func (c Config) ForServer() server.Config {
+	return server.Config{
+		Addr:              ":" + strconv.Itoa(c.Server.Port),
+		ReadTimeout:       time.Duration(c.Server.ReadTimeout),
+		ReadHeaderTimeout: time.Duration(c.Server.ReadHeaderTimeout),
+		WriteTimeout:      time.Duration(c.Server.WriteTimeout),
+		IdleTimeout:       time.Duration(c.Server.IdleTimeout),
+		ShutdownTimeout:   time.Duration(c.Server.ShutdownTimeout),
+		TLS: server.TLSConfig{
+			CertFile:       c.Server.TLS.CertFile,
+			KeyFile:        c.Server.TLS.KeyFile,
+			ClientCAFile:   c.Server.TLS.ClientCAFile,
+			ClientAuth:     strings.ToLower(c.Server.TLS.ClientAuth),
+			ReloadInterval: time.Duration(c.Server.TLS.ReloadInterval),
+		},
+		H2C: c.Server.H2C,
+	}
+}
+
+func (c Config) ForLogger() logger.Config {
+	return logger.Config{
+		Format: c.Logging.Format,
+		Level:  c.Logging.Level,
+		Output: c.Logging.Output,
+	}
+}
+
+func (c Config) ForTracing() tracing.Config {
+	return tracing.Config{
+		Exporter:    strings.ToLower(c.Tracing.Exporter),
+		ServiceName: c.Tracing.ServiceName,
+		SampleRatio: c.Tracing.SampleRatio,
+	}
+}
+
+func (c Config) ForRedaction() middlewares.RedactionConfig {
+	redaction := middlewares.DefaultRedactionConfig()
+	redaction.Fields = append(redaction.Fields, c.Logging.Redact.Fields...)
+	redaction.Headers = append(redaction.Headers, c.Logging.Redact.Headers...)
+
+	redaction.Patterns = nil
+	for _, name := range c.Logging.Redact.Patterns {
+		redaction.Patterns = append(redaction.Patterns, middlewares.RedactionPatterns[name])
+	}
+	if c.Logging.Redact.Regex != "" {
+		redaction.Patterns = append(redaction.Patterns, regexp.MustCompile(c.Logging.Redact.Regex))
+	}
+
+	redaction.Mode = middlewares.RedactionMode(strings.ToLower(c.Logging.Redact.Mode))
+	return redaction
+}
+
+func (c Config) ForBodyLogging() middlewares.BodyLoggingConfig {
+	return middlewares.BodyLoggingConfig{
+		MaxBodySize:  c.Logging.Body.MaxSize,
+		ContentTypes: c.Logging.Body.ContentTypes,
+		SkipRoutes:   c.Logging.Body.SkipRoutes,
+		SampleRate:   c.Logging.Body.SampleRate,
+	}
+}
+
+func (c Config) ForRateLimit() middlewares.RateLimitConfig {
+	// Routes were checked by Validate, so parsing cannot fail here.
+	routes, _ := middlewares.ParseRateLimitRoutes(strings.Join(c.RateLimit.Routes, ","))
+
+	burst := c.RateLimit.Burst
+	if burst == 0 {
+		burst = int(math.Ceil(c.RateLimit.RPS))
+	}
+
+	return middlewares.RateLimitConfig{
+		Default: middlewares.RateLimitRule{Rate: c.RateLimit.RPS, Burst: burst},
+		Routes:  routes,
+		IdleTTL: time.Duration(c.RateLimit.IdleTTL),
+	}
+}
+
+func (c Config) ForCORS() middlewares.CORSConfig {
+	return middlewares.CORSConfig{
+		AllowedOrigins:   c.CORS.AllowedOrigins,
+		AllowedMethods:   c.CORS.AllowedMethods,
+		AllowedHeaders:   c.CORS.AllowedHeaders,
+		ExposedHeaders:   c.CORS.ExposedHeaders,
+		AllowCredentials: c.CORS.AllowCredentials,
+		MaxAge:           time.Duration(c.CORS.MaxAge),
+	}
+}
+
+func (c Config) ForCompression() middlewares.CompressionConfig {
+	return middlewares.CompressionConfig{
+		Encodings:    c.Compression.Encodings,
+		MinSize:      c.Compression.MinSize,
+		ContentTypes: c.Compression.ContentTypes,
+	}
+}
+
+func (c Config) ForIdempotency() middlewares.IdempotencyConfig {
+	return middlewares.IdempotencyConfig{
+		TTL:         time.Duration(c.Idempotency.TTL),
+		MaxBodySize: int64(c.Idempotency.MaxBodySize),
+		MaxEntries:  c.Idempotency.MaxEntries,
+	}
+}
+
+func (c Config) ForWebhooks() webhooks.Config {
+	return webhooks.Config{
+		Workers:         c.Webhooks.Workers,
+		QueueSize:       c.Webhooks.QueueSize,
+		Timeout:         time.Duration(c.Webhooks.Timeout),
+		MaxAttempts:     c.Webhooks.MaxAttempts,
+		InitialBackoff:  time.Duration(c.Webhooks.InitialBackoff),
+		MaxBackoff:      time.Duration(c.Webhooks.MaxBackoff),
+		DeliveryLogSize: c.Webhooks.DeliveryLogSize,
+		DeadLetterSize:  c.Webhooks.DeadLetterSize,
+
+		AllowPrivateTargets: c.Webhooks.AllowPrivateTargets,
+	}
+}
+
+func (c Config) ForPresence() presence.Config {
+	return presence.Config{
+		PingInterval: time.Duration(c.Presence.PingInterval),
+		StaleAfter:   time.Duration(c.Presence.StaleAfter),
+		MaxSessions:  c.Presence.MaxSessions,
+	}
+}
+
+func (c Config) ForGraphQL() handlers.GraphQLConfig {
+	return handlers.GraphQLConfig{
+		MaxDepth:      c.GraphQL.MaxDepth,
+		MaxComplexity: c.GraphQL.MaxComplexity,
+	}
+}
+
+func (c Config) ForGRPC() grpcapi.Config {
+	return grpcapi.Config{Addr: ":" + strconv.Itoa(c.GRPC.Port)}
+}
+
+func (c Config) ForPersistence() repositories.PersistenceConfig {
+	return repositories.PersistenceConfig{
+		Dir:              c.Storage.DataDir,
+		Fsync:            repositories.FsyncPolicy(strings.ToLower(c.Storage.Fsync)),
+		FsyncInterval:    time.Duration(c.Storage.FsyncInterval),
+		SnapshotInterval: time.Duration(c.Storage.SnapshotInterval),
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// applyEnv walks config and overrides every field tagged `env:"NAME"` for
+// which lookup finds a variable. Lists are comma separated. An empty variable
+// clears a list, and leaves scalar fields at their current value.
+func applyEnv(config *Config, lookup func(string) (string, bool)) error {
+	var errs []error
+	walkEnv(reflect.ValueOf(config).Elem(), func(field reflect.Value, name string) {
+		value, exists := lookup(name)
+		if !exists {
+			return
+		}
+		if err := setField(field, value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: %w", name, value, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// walkEnv calls visit for every field with an env tag, descending into nested
+// structs.
+func walkEnv(value reflect.Value, visit func(field reflect.Value, name string)) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		structField := value.Type().Field(i)
+
+		if name := structField.Tag.Get("env"); name != "" {
+			visit(field, name)
+			continue
+		}
+		if field.Kind() == reflect.Struct {
+			walkEnv(field, visit)
+		}
+	}
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+func setField(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Slice {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+		return nil
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	if field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("not an integer")
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("not a number")
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("not a boolean")
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
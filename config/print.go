@@ -0,0 +1,53 @@
+package config
+
+import (
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+const maskedValue = "******"
+
+// Masked returns a copy of c with every field tagged `secret:"true"` replaced
+// by a mask, so it can be shown or logged.
+func (c Config) Masked() Config {
+	masked := c
+	maskSecrets(reflect.ValueOf(&masked).Elem())
+	return masked
+}
+
+func maskSecrets(value reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if value.Type().Field(i).Tag.Get("secret") != "true" {
+			if field.Kind() == reflect.Struct {
+				maskSecrets(field)
+			}
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.String:
+			if field.String() != "" {
+				field.SetString(maskedValue)
+			}
+		case reflect.Slice:
+			masked := make([]string, field.Len())
+			for j := range masked {
+				masked[j] = maskedValue
+			}
+			field.Set(reflect.ValueOf(masked))
+		}
+	}
+}
+
+// Print writes the effective configuration as YAML with secrets masked.
+func (c Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Masked()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/brothergiez/restful-api/logger"
+	"github.com/brothergiez/restful-api/middlewares"
+)
+
+// Validate reports every invalid setting at once so they can all be fixed
+// before the next start.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ReadTimeout >= 0, "server.readTimeout must not be negative")
+	check(c.Server.ReadHeaderTimeout >= 0, "server.readHeaderTimeout must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.writeTimeout must not be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idleTimeout must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdownTimeout must be positive")
+	tls := c.Server.TLS
+	check((tls.CertFile == "") == (tls.KeyFile == ""), "server.tls.certFile and server.tls.keyFile must be set together")
+	check(tls.ClientCAFile == "" || tls.CertFile != "", "server.tls.clientCAFile requires server.tls.certFile and server.tls.keyFile")
+	check(oneOf(tls.ClientAuth, "require", "verify_if_given"), "server.tls.clientAuth must be require or verify_if_given, got %q", tls.ClientAuth)
+	check(tls.ReloadInterval >= 0, "server.tls.reloadInterval must not be negative")
+	check(!c.Server.H2C || tls.CertFile == "", "server.h2c cannot be combined with TLS, which negotiates HTTP/2 itself")
+
+	check(c.Storage.Driver == "memory", "storage.driver must be \"memory\", got %q", c.Storage.Driver)
+	check(oneOf(c.Storage.Fsync, "always", "interval", "never"), "storage.fsync must be always, interval or never, got %q", c.Storage.Fsync)
+	check(!strings.EqualFold(c.Storage.Fsync, "interval") || c.Storage.FsyncInterval > 0,
+		"storage.fsyncInterval must be positive when storage.fsync is interval")
+	check(c.Storage.SnapshotInterval >= 0, "storage.snapshotInterval must not be negative")
+
+	check(c.Articles.BulkMaxOperations > 0, "articles.bulkMaxOperations must be positive")
+	check(c.Articles.StreamReplaySize >= 0, "articles.streamReplaySize must not be negative")
+	check(c.Articles.StreamHeartbeat > 0, "articles.streamHeartbeat must be positive")
+
+	_, err := logger.ParseLevel(c.Logging.Level)
+	check(err == nil, "logging.level must be debug, info, warn or error, got %q", c.Logging.Level)
+	check(oneOf(c.Logging.Format, "json", "text"), "logging.format must be json or text, got %q", c.Logging.Format)
+	check(c.Logging.Output != "", "logging.output must not be empty")
+	check(oneOf(c.Logging.Redact.Mode, string(middlewares.RedactFull), string(middlewares.RedactPartial)),
+		"logging.redact.mode must be full or partial, got %q", c.Logging.Redact.Mode)
+	for _, name := range c.Logging.Redact.Patterns {
+		_, exists := middlewares.RedactionPatterns[name]
+		check(exists, "logging.redact.patterns: unknown pattern %q", name)
+	}
+	if c.Logging.Redact.Regex != "" {
+		_, err := regexp.Compile(c.Logging.Redact.Regex)
+		check(err == nil, "logging.redact.regex: %v", err)
+	}
+	check(c.Logging.Body.SampleRate >= 0 && c.Logging.Body.SampleRate <= 1,
+		"logging.body.sampleRate must be between 0 and 1, got %v", c.Logging.Body.SampleRate)
+
+	check(oneOf(c.Tracing.Exporter, "none", "stdout", "otlp"), "tracing.exporter must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1,
+		"tracing.sampleRatio must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+
+	check(c.RateLimit.RPS >= 0, "rateLimit.rps must not be negative")
+	check(c.RateLimit.Burst >= 0, "rateLimit.burst must not be negative")
+	check(c.RateLimit.IdleTTL > 0, "rateLimit.idleTTL must be positive")
+	_, err = middlewares.ParseRateLimitRoutes(strings.Join(c.RateLimit.Routes, ","))
+	check(err == nil, "rateLimit.routes: %v", err)
+
+	for _, key := range c.Auth.APIKeys {
+		check(len(key) >= 16, "auth.apiKeys: keys must be at least 16 characters")
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(origin == "*" || strings.Count(origin, "*") <= 1 && strings.Contains(origin, "://"),
+			"cors.allowedOrigins: %q must be \"*\" or a scheme://host origin with at most one wildcard", origin)
+	}
+	check(len(c.CORS.AllowedOrigins) == 0 || len(c.CORS.AllowedMethods) > 0, "cors.allowedMethods must not be empty when CORS is enabled")
+	check(!c.CORS.AllowCredentials || !slices.Contains(c.CORS.AllowedOrigins, "*"),
+		"cors.allowCredentials cannot be combined with the \"*\" origin; list the origins instead")
+	check(c.CORS.MaxAge >= 0, "cors.maxAge must not be negative")
+
+	for _, encoding := range c.Compression.Encodings {
+		check(oneOf(encoding, middlewares.CompressionEncodings...), "compression.encodings must be zstd, br or gzip, got %q", encoding)
+	}
+	check(c.Compression.MinSize >= 0, "compression.minSize must not be negative")
+
+	check(c.Idempotency.TTL > 0, "idempotency.ttl must be positive")
+	check(c.Idempotency.MaxBodySize > 0, "idempotency.maxBodySize must be positive")
+	check(c.Idempotency.MaxEntries > 0, "idempotency.maxEntries must be positive")
+
+	check(c.Webhooks.Workers > 0, "webhooks.workers must be positive")
+	check(c.Webhooks.QueueSize > 0, "webhooks.queueSize must be positive")
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.maxAttempts must be positive")
+	check(c.Webhooks.InitialBackoff > 0 && c.Webhooks.MaxBackoff >= c.Webhooks.InitialBackoff,
+		"webhooks.initialBackoff must be positive and at most webhooks.maxBackoff")
+	check(c.Webhooks.DeliveryLogSize >= 0 && c.Webhooks.DeadLetterSize >= 0,
+		"webhooks.deliveryLogSize and webhooks.deadLetterSize must not be negative")
+
+	check(c.Presence.PingInterval > 0 && c.Presence.StaleAfter > c.Presence.PingInterval,
+		"presence.pingInterval must be positive and shorter than presence.staleAfter")
+	check(c.Presence.MaxSessions > 0, "presence.maxSessions must be positive")
+	check(c.GraphQL.MaxDepth > 0, "graphql.maxDepth must be positive")
+	check(c.GraphQL.MaxComplexity > 0, "graphql.maxComplexity must be positive")
+	check(c.GRPC.Port >= 0 && c.GRPC.Port <= 65535, "grpc.port must be between 0 and 65535, got %d", c.GRPC.Port)
+	check(c.GRPC.Port == 0 || c.GRPC.Port != c.Server.Port, "grpc.port must differ from server.port")
+
+	return errors.Join(errs...)
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if strings.EqualFold(value, a) {
+			return true
+		}
+	}
+	return false
+}
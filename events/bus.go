@@ -0,0 +1,205 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Handler receives published events.
+type Handler func(ctx context.Context, event Event)
+
+// On adapts a handler for a single event type. Other events are ignored.
+func On[T Event](handler func(ctx context.Context, event T)) Handler {
+	return func(ctx context.Context, event Event) {
+		if e, ok := event.(T); ok {
+			handler(ctx, e)
+		}
+	}
+}
+
+type Option func(*Bus)
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(b *Bus) {
+		b.logger = logger
+	}
+}
+
+type delivery struct {
+	ctx   context.Context
+	event Event
+}
+
+// Subscription is a handler registered on a Bus.
+type Subscription struct {
+	name    string
+	handler Handler
+	// queue is nil for synchronous subscriptions.
+	queue   chan delivery
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+// Dropped returns the number of events an asynchronous subscription missed
+// because its queue was full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Bus fans published events out to its subscribers. Synchronous subscribers
+// run in the publishing goroutine, so the write that caused the event
+// returns only after they are done and should stay fast. Asynchronous
+// subscribers get a bounded queue and a goroutine of their own; when the
+// queue is full the event is dropped for that subscriber rather than slowing
+// down writes.
+type Bus struct {
+	logger *slog.Logger
+
+	mu            sync.RWMutex
+	subscriptions []*Subscription
+	closed        bool
+}
+
+func NewBus(opts ...Option) *Bus {
+	b := &Bus{logger: slog.Default()}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Subscribe registers a synchronous handler.
+func (b *Bus) Subscribe(name string, handler Handler) *Subscription {
+	s := &Subscription{name: name, handler: handler}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions = append(b.subscriptions, s)
+	return s
+}
+
+// SubscribeAsync registers a handler that runs in its own goroutine and
+// receives events through a queue of queueSize events, in the order the
+// Publish calls handed them over.
+func (b *Bus) SubscribeAsync(name string, queueSize int, handler Handler) *Subscription {
+	s := &Subscription{
+		name:    name,
+		handler: handler,
+		queue:   make(chan delivery, queueSize),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		for d := range s.queue {
+			b.call(s, d.ctx, d.event)
+		}
+	}()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(s.queue)
+		return s
+	}
+	b.subscriptions = append(b.subscriptions, s)
+	return s
+}
+
+// Unsubscribe removes a subscription. An asynchronous subscription still
+// handles the events already in its queue.
+func (b *Bus) Unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := slices.Index(b.subscriptions, s)
+	if i < 0 {
+		return
+	}
+	b.subscriptions = slices.Delete(b.subscriptions, i, i+1)
+	if s.queue != nil {
+		close(s.queue)
+	}
+}
+
+// Publish hands events to every subscriber, in order. Concurrent calls are
+// not ordered against each other, so a publisher whose events must arrive in
+// order makes one call at a time. Asynchronous
+// subscribers get a context that is not cancelled with ctx, since they may
+// run after the request that caused the events has finished. Once the bus is
+// closed only synchronous subscribers receive events.
+func (b *Bus) Publish(ctx context.Context, events ...Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	b.mu.RLock()
+	var inline []*Subscription
+	detached := context.WithoutCancel(ctx)
+	for _, s := range b.subscriptions {
+		if s.queue == nil {
+			inline = append(inline, s)
+			continue
+		}
+		// Queues are only closed under the write lock, so sending while
+		// holding the read lock is safe.
+		for _, event := range events {
+			select {
+			case s.queue <- delivery{ctx: detached, event: event}:
+			default:
+				dropped := s.dropped.Add(1)
+				b.logger.Warn("Event subscriber queue full, event dropped",
+					"subscriber", s.name, "event", event.Type(), "dropped", dropped)
+			}
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, s := range inline {
+		for _, event := range events {
+			b.call(s, ctx, event)
+		}
+	}
+}
+
+// call runs a handler, containing any panic so one faulty subscriber cannot
+// fail the write that published the event or stop its own queue.
+func (b *Bus) call(s *Subscription, ctx context.Context, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.logger.Error("Event subscriber panicked", "subscriber", s.name, "event", event.Type(), "panic", r)
+		}
+	}()
+	s.handler(ctx, event)
+}
+
+// Close stops the asynchronous subscribers after they have handled their
+// queued events, or when ctx is done. It is registered as a server shutdown
+// hook.
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	var pending []*Subscription
+	for _, s := range b.subscriptions {
+		if s.queue != nil {
+			close(s.queue)
+			pending = append(pending, s)
+		}
+	}
+	b.subscriptions = slices.DeleteFunc(b.subscriptions, func(s *Subscription) bool { return s.queue != nil })
+	b.mu.Unlock()
+
+	for _, s := range pending {
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			return errors.Join(errors.New("event subscribers did not drain"), ctx.Err())
+		}
+	}
+	return nil
+}
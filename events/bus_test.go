@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brothergiez/restful-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey struct{}
+
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) handle(ctx context.Context, event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) received() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+func created(id int) ArticleCreated {
+	return ArticleCreated{Article: models.Article{ID: id}}
+}
+
+func TestBusSyncSubscribers(t *testing.T) {
+	bus := NewBus()
+	var all recorder
+	var updates []ArticleUpdated
+	bus.Subscribe("all", all.handle)
+	bus.Subscribe("updates", On(func(ctx context.Context, e ArticleUpdated) {
+		assert.Equal(t, "request", ctx.Value(ctxKey{}))
+		updates = append(updates, e)
+	}))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+	update := ArticleUpdated{Article: models.Article{ID: 1, Title: "New"}, Previous: models.Article{ID: 1, Title: "Old"}}
+	bus.Publish(ctx, created(1), update, ArticleDeleted{Article: models.Article{ID: 1}})
+
+	// Synchronous subscribers are done when Publish returns.
+	assert.Equal(t, []Event{created(1), update, ArticleDeleted{Article: models.Article{ID: 1}}}, all.received())
+	assert.Equal(t, []ArticleUpdated{update}, updates)
+	assert.Equal(t, TypeArticleUpdated, update.Type())
+	assert.Equal(t, "New", update.Subject().Title)
+}
+
+func TestBusAsyncSubscribers(t *testing.T) {
+	bus := NewBus()
+	release := make(chan struct{})
+	var slow recorder
+	subscription := bus.SubscribeAsync("slow", 2, func(ctx context.Context, e Event) {
+		<-release
+		assert.NoError(t, ctx.Err())
+		slow.handle(ctx, e)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// The first event is taken by the subscriber goroutine, two wait in the
+	// queue and the rest are dropped.
+	bus.Publish(ctx, created(1))
+	assert.Eventually(t, func() bool { return len(subscription.queue) == 0 }, time.Second, time.Millisecond)
+	bus.Publish(ctx, created(2), created(3), created(4), created(5))
+	cancel()
+
+	assert.Equal(t, uint64(2), subscription.Dropped())
+	close(release)
+	assert.NoError(t, bus.Close(context.Background()))
+	assert.Equal(t, []Event{created(1), created(2), created(3)}, slow.received())
+
+	// After Close only synchronous subscribers are called.
+	var late recorder
+	bus.Subscribe("late", late.handle)
+	bus.SubscribeAsync("late-async", 1, func(context.Context, Event) { t.Error("called after Close") })
+	bus.Publish(context.Background(), created(6))
+	assert.Len(t, late.received(), 1)
+	assert.NoError(t, bus.Close(context.Background()))
+}
+
+func TestBusContainsPanics(t *testing.T) {
+	bus := NewBus()
+	var after recorder
+	bus.Subscribe("faulty", func(context.Context, Event) { panic("boom") })
+	bus.Subscribe("after", after.handle)
+	asyncDone := make(chan struct{}, 2)
+	bus.SubscribeAsync("faulty-async", 2, func(_ context.Context, e Event) {
+		asyncDone <- struct{}{}
+		if e.Subject().ID == 1 {
+			panic("boom")
+		}
+	})
+
+	bus.Publish(context.Background(), created(1), created(2))
+
+	assert.Len(t, after.received(), 2)
+	<-asyncDone
+	<-asyncDone
+	assert.NoError(t, bus.Close(context.Background()))
+}
+
+func TestBusUnsubscribe(t *testing.T) {
+	bus := NewBus()
+	var inline, async recorder
+	syncSubscription := bus.Subscribe("sync", inline.handle)
+	asyncSubscription := bus.SubscribeAsync("async", 10, async.handle)
+
+	bus.Publish(context.Background(), created(1))
+	bus.Unsubscribe(syncSubscription)
+	bus.Unsubscribe(asyncSubscription)
+	bus.Unsubscribe(asyncSubscription)
+	bus.Publish(context.Background(), created(2))
+
+	<-asyncSubscription.done
+	assert.Equal(t, []Event{created(1)}, inline.received())
+	assert.Equal(t, []Event{created(1)}, async.received())
+}
+
+func TestBusCloseTimeout(t *testing.T) {
+	bus := NewBus()
+	release := make(chan struct{})
+	defer close(release)
+	bus.SubscribeAsync("stuck", 1, func(context.Context, Event) { <-release })
+	bus.Publish(context.Background(), created(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, bus.Close(ctx), context.DeadlineExceeded)
+}
@@ -0,0 +1,53 @@
+package events
+
+import (
+	"time"
+
+	"github.com/brothergiez/restful-api/models"
+)
+
+// Event types, as returned by Event.Type.
+const (
+	TypeArticleCreated = "article.created"
+	TypeArticleUpdated = "article.updated"
+	TypeArticleDeleted = "article.deleted"
+)
+
+// Event is a change to an article, published after it has been stored.
+type Event interface {
+	// Type is the event name, one of the Type* constants.
+	Type() string
+	// Subject is the article as it is after the change, or as it was before
+	// it was deleted.
+	Subject() models.Article
+	// Time is when the change was stored.
+	Time() time.Time
+}
+
+type ArticleCreated struct {
+	Article models.Article
+	At      time.Time
+}
+
+func (e ArticleCreated) Type() string            { return TypeArticleCreated }
+func (e ArticleCreated) Subject() models.Article { return e.Article }
+func (e ArticleCreated) Time() time.Time         { return e.At }
+
+type ArticleUpdated struct {
+	Article  models.Article
+	Previous models.Article
+	At       time.Time
+}
+
+func (e ArticleUpdated) Type() string            { return TypeArticleUpdated }
+func (e ArticleUpdated) Subject() models.Article { return e.Article }
+func (e ArticleUpdated) Time() time.Time         { return e.At }
+
+type ArticleDeleted struct {
+	Article models.Article
+	At      time.Time
+}
+
+func (e ArticleDeleted) Type() string            { return TypeArticleDeleted }
+func (e ArticleDeleted) Subject() models.Article { return e.Article }
+func (e ArticleDeleted) Time() time.Time         { return e.At }
@@ -0,0 +1,159 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultStreamReplaySize is the number of recent events a Stream keeps for
+// clients that reconnect.
+const DefaultStreamReplaySize = 1000
+
+// Sequenced is an event numbered by a Stream. IDs start at 1 and increase by
+// one per event, so a client can resume after the last ID it saw.
+type Sequenced struct {
+	ID    uint64
+	Event Event
+}
+
+// Listener receives the events handled by a Stream after it started
+// listening.
+type Listener struct {
+	stream *Stream
+	events chan Sequenced
+}
+
+// Events is closed when the listener falls behind by more than its buffer,
+// when it is closed, or when the stream is closed. A client that reconnects
+// with the last ID it received gets the missed events from the replay.
+func (l *Listener) Events() <-chan Sequenced {
+	return l.events
+}
+
+// Close stops the listener.
+func (l *Listener) Close() {
+	l.stream.mu.Lock()
+	defer l.stream.mu.Unlock()
+	l.stream.remove(l)
+}
+
+// Stream numbers events and fans them out to listeners, keeping the most
+// recent ones in a ring buffer so clients can resume where they left off.
+// Event n is kept at index (n-1) % size until event n+size replaces it.
+// It is meant to be an asynchronous bus subscriber: Handle never blocks on a
+// listener.
+type Stream struct {
+	mu        sync.Mutex
+	replay    []Sequenced
+	size      int
+	lastID    uint64
+	listeners map[*Listener]struct{}
+	closed    bool
+}
+
+func NewStream(replaySize int) *Stream {
+	s := &Stream{
+		size:      max(replaySize, 0),
+		listeners: map[*Listener]struct{}{},
+	}
+	s.replay = make([]Sequenced, s.size)
+	return s
+}
+
+// Handle numbers event, stores it in the replay buffer and sends it to every
+// listener. Listeners whose buffer is full are dropped.
+func (s *Stream) Handle(_ context.Context, event Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+
+	s.lastID++
+	sequenced := Sequenced{ID: s.lastID, Event: event}
+	if s.size > 0 {
+		s.replay[s.index(sequenced.ID)] = sequenced
+	}
+
+	for l := range s.listeners {
+		select {
+		case l.events <- sequenced:
+		default:
+			s.remove(l)
+		}
+	}
+}
+
+// Listen starts a listener for new events with room for buffer pending events.
+func (s *Stream) Listen(buffer int) *Listener {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listen(buffer)
+}
+
+// Resume starts a listener like Listen and also returns the buffered events
+// after lastID. complete is false when some events after lastID are no longer
+// buffered, or when lastID is unknown, e.g. because it was issued before a
+// restart; the client then has to reload its state.
+func (s *Stream) Resume(lastID uint64, buffer int) (replay []Sequenced, l *Listener, complete bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Events after lastID were missed unless the replay buffer reaches back
+	// to the first of them. An empty buffer covers nothing.
+	oldest := s.oldestID()
+	missed := lastID < s.lastID
+	covered := oldest <= s.lastID && oldest <= lastID+1
+	complete = lastID <= s.lastID && (!missed || covered)
+	from := oldest
+	if complete {
+		from = max(from, lastID+1)
+	}
+	for id := from; id <= s.lastID; id++ {
+		replay = append(replay, s.replay[s.index(id)])
+	}
+	return replay, s.listen(buffer), complete
+}
+
+// oldestID is the ID of the oldest buffered event, or s.lastID+1 when none
+// is buffered.
+func (s *Stream) oldestID() uint64 {
+	if s.lastID < uint64(s.size) {
+		return 1
+	}
+	return s.lastID - uint64(s.size) + 1
+}
+
+func (s *Stream) index(id uint64) int {
+	return int((id - 1) % uint64(s.size))
+}
+
+// Close ends every listener. Later events are ignored. It is registered to
+// run when the server starts draining, since open streams would otherwise
+// hold the shutdown until its timeout.
+func (s *Stream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for l := range s.listeners {
+		s.remove(l)
+	}
+}
+
+func (s *Stream) listen(buffer int) *Listener {
+	l := &Listener{stream: s, events: make(chan Sequenced, buffer)}
+	if s.closed {
+		close(l.events)
+		return l
+	}
+	s.listeners[l] = struct{}{}
+	return l
+}
+
+// remove must be called with s.mu held.
+func (s *Stream) remove(l *Listener) {
+	if _, ok := s.listeners[l]; ok {
+		delete(s.listeners, l)
+		close(l.events)
+	}
+}
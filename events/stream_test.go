@@ -0,0 +1,111 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ids(events []Sequenced) []uint64 {
+	var ids []uint64
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestStreamListen(t *testing.T) {
+	stream := NewStream(10)
+	stream.Handle(context.Background(), created(1))
+	l := stream.Listen(2)
+
+	stream.Handle(context.Background(), created(2))
+	e := <-l.Events()
+	assert.Equal(t, uint64(2), e.ID)
+	assert.Equal(t, created(2), e.Event)
+
+	// A listener that falls behind is dropped.
+	stream.Handle(context.Background(), created(3))
+	stream.Handle(context.Background(), created(4))
+	stream.Handle(context.Background(), created(5))
+	assert.Equal(t, uint64(3), (<-l.Events()).ID)
+	assert.Equal(t, uint64(4), (<-l.Events()).ID)
+	_, open := <-l.Events()
+	assert.False(t, open)
+
+	other := stream.Listen(1)
+	other.Close()
+	other.Close()
+	_, open = <-other.Events()
+	assert.False(t, open)
+}
+
+func TestStreamResume(t *testing.T) {
+	stream := NewStream(3)
+	for i := 1; i <= 5; i++ {
+		stream.Handle(context.Background(), created(i))
+	}
+
+	replay, l, complete := stream.Resume(3, 1)
+	assert.True(t, complete)
+	assert.Equal(t, []uint64{4, 5}, ids(replay))
+	stream.Handle(context.Background(), created(6))
+	assert.Equal(t, uint64(6), (<-l.Events()).ID)
+
+	replay, _, complete = stream.Resume(6, 1)
+	assert.True(t, complete)
+	assert.Empty(t, replay)
+
+	// Event 2 is no longer buffered.
+	replay, _, complete = stream.Resume(1, 1)
+	assert.False(t, complete)
+	assert.Equal(t, []uint64{4, 5, 6}, ids(replay))
+
+	// An ID the stream never issued comes from before a restart.
+	replay, _, complete = stream.Resume(42, 1)
+	assert.False(t, complete)
+	assert.Equal(t, []uint64{4, 5, 6}, ids(replay))
+}
+
+func TestStreamResumeBeforeBufferFills(t *testing.T) {
+	stream := NewStream(3)
+	replay, _, complete := stream.Resume(0, 1)
+	assert.True(t, complete)
+	assert.Empty(t, replay)
+
+	stream.Handle(context.Background(), created(1))
+	stream.Handle(context.Background(), created(2))
+	replay, _, complete = stream.Resume(0, 1)
+	assert.True(t, complete)
+	assert.Equal(t, []uint64{1, 2}, ids(replay))
+}
+
+func TestStreamResumeWithoutReplayBuffer(t *testing.T) {
+	stream := NewStream(0)
+	stream.Handle(context.Background(), created(1))
+	stream.Handle(context.Background(), created(2))
+
+	replay, _, complete := stream.Resume(1, 1)
+	assert.False(t, complete)
+	assert.Empty(t, replay)
+
+	replay, _, complete = stream.Resume(2, 1)
+	assert.True(t, complete)
+	assert.Empty(t, replay)
+}
+
+func TestStreamClose(t *testing.T) {
+	stream := NewStream(3)
+	l := stream.Listen(1)
+
+	stream.Close()
+	_, open := <-l.Events()
+	assert.False(t, open)
+
+	stream.Handle(context.Background(), created(1))
+	_, late, _ := stream.Resume(0, 1)
+	_, open = <-late.Events()
+	assert.False(t, open)
+	late.Close()
+}
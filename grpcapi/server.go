@@ -0,0 +1,236 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"time"
+
+	"github.com/brothergiez/restful-api/articlepb"
+	"github.com/brothergiez/restful-api/middlewares"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// apiKeyMetadata is the metadata key holding the API key, the counterpart of
+// the X-API-Key header.
+const apiKeyMetadata = "x-api-key"
+
+type Config struct {
+	Addr string
+}
+
+type Option func(*Server)
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithAPIKeys identifies the callers that send one of keys in their x-api-key
+// metadata, so they get rate limit buckets of their own. Calls without a
+// matching key are served too.
+func WithAPIKeys(keys []string) Option {
+	return func(s *Server) {
+		if len(keys) > 0 {
+			s.validKey = middlewares.APIKeyMatcher(keys)
+		}
+	}
+}
+
+// WithTLS serves TLS with config. Passing the HTTP server's configuration
+// shares its certificate, reloading and client certificate checks. A nil
+// config keeps the server on cleartext.
+func WithTLS(config *tls.Config) Option {
+	return func(s *Server) {
+		s.tls = config
+	}
+}
+
+// WithRateLimit limits calls with the token buckets of the HTTP API. A method
+// is looked up in config.Routes as "POST /<service>/<method>", the request
+// line gRPC sends, and falls back to config.Default. Clients are told apart by
+// their API key once it has been checked, otherwise by their address.
+func WithRateLimit(config middlewares.RateLimitConfig, store middlewares.RateLimitStore) Option {
+	return func(s *Server) {
+		s.rateLimit = config
+		s.limiter = store
+	}
+}
+
+// Server serves ArticleService, and reflection for tools such as grpcurl.
+// Every call is rate limited, logged and protected from panics.
+type Server struct {
+	config    Config
+	logger    *slog.Logger
+	validKey  func(string) bool
+	tls       *tls.Config
+	rateLimit middlewares.RateLimitConfig
+	limiter   middlewares.RateLimitStore
+	grpc      *grpc.Server
+}
+
+func NewServer(service articlepb.ArticleServiceServer, config Config, opts ...Option) *Server {
+	s := &Server{
+		config: config,
+		logger: slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	serverOptions := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	}
+	if s.tls != nil {
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(s.tls)))
+	}
+	s.grpc = grpc.NewServer(serverOptions...)
+	articlepb.RegisterArticleServiceServer(s.grpc, service)
+	reflection.Register(s.grpc)
+	return s
+}
+
+// Listen opens the configured address, so a port in use is reported before
+// the server starts in the background.
+func (s *Server) Listen() (net.Listener, error) {
+	return net.Listen("tcp", s.config.Addr)
+}
+
+// Serve serves on listener until Shutdown, after which it returns nil.
+func (s *Server) Serve(listener net.Listener) error {
+	s.logger.Info("Starting gRPC server", "addr", listener.Addr().String(), "tls", s.tls != nil)
+	return s.grpc.Serve(listener)
+}
+
+// Shutdown stops accepting calls and waits for the running ones, cancelling
+// them when ctx ends first. It is registered as a server shutdown hook.
+func (s *Server) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.grpc.Stop()
+		<-stopped
+		return ctx.Err()
+	}
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	startTime := time.Now()
+	defer func() {
+		err = s.finish(ctx, info.FullMethod, startTime, recover(), err)
+	}()
+
+	if err := s.limit(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	startTime := time.Now()
+	defer func() {
+		err = s.finish(stream.Context(), info.FullMethod, startTime, recover(), err)
+	}()
+
+	if err := s.limit(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// limit takes a token for the call. Calls with a wrong key are limited by
+// address, like the HTTP API, so made-up keys do not get fresh buckets.
+func (s *Server) limit(ctx context.Context, method string) error {
+	if s.limiter == nil {
+		return nil
+	}
+	route := "POST " + method
+	rule, exists := s.rateLimit.Routes[route]
+	if !exists {
+		rule = s.rateLimit.Default
+	}
+	if rule.Rate <= 0 || rule.Burst < 1 {
+		return nil
+	}
+	client := "ip:" + peerHost(ctx)
+	if apiKey := s.checkedKey(ctx); apiKey != "" {
+		client = "key:" + apiKey
+	}
+	if allowed, _, _ := s.limiter.Take(route+"|"+client, rule, time.Now()); !allowed {
+		return status.Error(codes.ResourceExhausted, "Too many requests")
+	}
+	return nil
+}
+
+// checkedKey returns the API key of the call if it is valid.
+func (s *Server) checkedKey(ctx context.Context) string {
+	if s.validKey == nil {
+		return ""
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(apiKeyMetadata)
+	if len(keys) != 1 || !s.validKey(keys[0]) {
+		return ""
+	}
+	return keys[0]
+}
+
+// peerHost returns the client address without the port.
+func peerHost(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// finish turns a panic into an internal error and logs the call, with the
+// same levels as the HTTP request log: server errors at error level, client
+// errors at warn level.
+func (s *Server) finish(ctx context.Context, method string, startTime time.Time, recovered any, err error) error {
+	if recovered != nil {
+		s.logger.ErrorContext(ctx, "Panic in gRPC handler", "method", method, "panic", recovered, "stack", string(debug.Stack()))
+		err = status.Error(codes.Internal, "Internal server error")
+	}
+
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Int64("duration_ms", time.Since(startTime).Milliseconds()),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	s.logger.LogAttrs(ctx, level, "rpc", attrs...)
+	return err
+}
@@ -0,0 +1,121 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"log/slog"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/brothergiez/restful-api/articlepb"
+	"github.com/brothergiez/restful-api/middlewares"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type panickingService struct {
+	articlepb.UnimplementedArticleServiceServer
+}
+
+func (panickingService) GetArticle(context.Context, *articlepb.GetArticleRequest) (*articlepb.Article, error) {
+	panic("boom")
+}
+
+func TestServerRecoversAndLogs(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	client := startServer(t, NewServer(panickingService{}, Config{}, WithLogger(logger)))
+
+	_, err := client.GetArticle(context.Background(), &articlepb.GetArticleRequest{Id: 1})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Contains(t, logs.String(), `"msg":"Panic in gRPC handler"`)
+	assert.Contains(t, logs.String(), `"level":"ERROR","msg":"rpc","method":"/articles.v1.ArticleService/GetArticle","code":"Internal"`)
+
+	// The server keeps serving.
+	_, err = client.SearchArticles(context.Background(), &articlepb.SearchArticlesRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	assert.Contains(t, logs.String(), `"level":"WARN","msg":"rpc","method":"/articles.v1.ArticleService/SearchArticles","code":"Unimplemented"`)
+}
+
+func TestServerRateLimit(t *testing.T) {
+	s := NewServer(NewArticleService(repositories.NewArticleRepository()), Config{},
+		WithAPIKeys([]string{"key-0123456789abcdef"}),
+		WithRateLimit(middlewares.RateLimitConfig{
+			Default: middlewares.RateLimitRule{Rate: 0.001, Burst: 2},
+			Routes: map[string]middlewares.RateLimitRule{
+				"POST /articles.v1.ArticleService/GetArticle": {Rate: 0.001, Burst: 1},
+			},
+		}, middlewares.NewMemoryRateLimitStore(time.Hour)))
+	client := startServer(t, s)
+	valid := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "key-0123456789abcdef")
+
+	_, err := client.GetArticle(valid, &articlepb.GetArticleRequest{Id: 1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.GetArticle(valid, &articlepb.GetArticleRequest{Id: 1})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Calls without a valid key are served, sharing the bucket of the address.
+	for _, key := range []string{"guess-1", "guess-2"} {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+		_, err = client.ListArticles(ctx, &articlepb.ListArticlesRequest{})
+		assert.NoError(t, err)
+	}
+	_, err = client.ListArticles(context.Background(), &articlepb.ListArticlesRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = client.ListArticles(valid, &articlepb.ListArticlesRequest{})
+	assert.NoError(t, err)
+}
+
+func TestServerTLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	s := NewServer(NewArticleService(repositories.NewArticleRepository()), Config{},
+		WithTLS(&tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}))
+	client := startServer(t, s, credentials.NewTLS(&tls.Config{RootCAs: roots, ServerName: "localhost"}))
+	_, err = client.ListArticles(context.Background(), &articlepb.ListArticlesRequest{})
+	assert.NoError(t, err)
+
+	plaintext := startServer(t, s)
+	_, err = plaintext.ListArticles(context.Background(), &articlepb.ListArticlesRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestServerShutdown(t *testing.T) {
+	s := NewServer(NewArticleService(repositories.NewArticleRepository()), Config{})
+	client := startServer(t, s)
+	_, err := client.ListArticles(context.Background(), &articlepb.ListArticlesRequest{})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, s.Shutdown(ctx))
+
+	_, err = client.ListArticles(context.Background(), &articlepb.ListArticlesRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
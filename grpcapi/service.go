@@ -0,0 +1,167 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/brothergiez/restful-api/articlepb"
+	"github.com/brothergiez/restful-api/models"
+	"github.com/brothergiez/restful-api/repositories"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageLimit = 10
+	// streamBatchSize is how many articles StreamArticles reads from the
+	// repository at a time.
+	streamBatchSize = 100
+)
+
+var errNotFound = status.Error(codes.NotFound, "Article not found")
+
+// storeError is a failed write. The client only learns that it failed; the
+// logging interceptor records the cause.
+type storeError struct {
+	cause error
+}
+
+func (e storeError) Error() string {
+	return e.cause.Error()
+}
+
+func (e storeError) GRPCStatus() *status.Status {
+	return status.New(codes.Internal, "Failed to store article")
+}
+
+// ArticleService serves the articles of the repository behind the REST
+// handlers, with the same rules.
+type ArticleService struct {
+	articlepb.UnimplementedArticleServiceServer
+	repo *repositories.ArticleRepository
+}
+
+func NewArticleService(repo *repositories.ArticleRepository) *ArticleService {
+	return &ArticleService{repo: repo}
+}
+
+func (s *ArticleService) CreateArticle(ctx context.Context, req *articlepb.CreateArticleRequest) (*articlepb.Article, error) {
+	article, err := s.repo.CreateArticle(ctx, models.Article{
+		Title:   req.GetTitle(),
+		Content: req.GetContent(),
+		Author:  req.GetAuthor(),
+		Tags:    req.GetTags(),
+	})
+	if err != nil {
+		return nil, storeError{cause: err}
+	}
+	return toProto(article), nil
+}
+
+func (s *ArticleService) UpdateArticle(ctx context.Context, req *articlepb.UpdateArticleRequest) (*articlepb.Article, error) {
+	if req.GetTitle() == "" || req.GetContent() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid input: Title and Content are required")
+	}
+	changes := repositories.ArticleChanges{Title: req.GetTitle(), Content: req.GetContent(), Author: req.Author}
+	if tags := req.GetTags(); len(tags) > 0 || req.GetClearTags() {
+		changes.Tags = &tags
+	}
+	article, err := s.repo.UpdateArticle(ctx, int(req.GetId()), changes)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, storeError{cause: err}
+	}
+	return toProto(article), nil
+}
+
+func (s *ArticleService) GetArticle(ctx context.Context, req *articlepb.GetArticleRequest) (*articlepb.Article, error) {
+	article, err := s.repo.GetArticle(ctx, int(req.GetId()))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toProto(article), nil
+}
+
+func (s *ArticleService) ListArticles(ctx context.Context, req *articlepb.ListArticlesRequest) (*articlepb.ListArticlesResponse, error) {
+	// Unset fields arrive as zero and take their defaults.
+	page, limit := int(req.GetPage()), int(req.GetLimit())
+	if page == 0 {
+		page = 1
+	}
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+	if page < 1 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid page number")
+	}
+	if limit < 1 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid limit number")
+	}
+
+	articles, total := s.repo.ListArticles(ctx, fromProtoFilter(req.GetFilter()), page, limit)
+	return &articlepb.ListArticlesResponse{
+		Articles:   toProtoList(articles),
+		Page:       int32(page),
+		Limit:      int32(limit),
+		Total:      int32(total),
+		TotalPages: int32((total + limit - 1) / limit),
+	}, nil
+}
+
+func (s *ArticleService) SearchArticles(ctx context.Context, req *articlepb.SearchArticlesRequest) (*articlepb.SearchArticlesResponse, error) {
+	articles := s.repo.SearchArticles(ctx, req.GetKeyword())
+	return &articlepb.SearchArticlesResponse{Articles: toProtoList(articles)}, nil
+}
+
+// StreamArticles reads the repository in batches by ID, like the NDJSON
+// export, so concurrent writes never make it skip or repeat an article.
+func (s *ArticleService) StreamArticles(req *articlepb.StreamArticlesRequest, stream articlepb.ArticleService_StreamArticlesServer) error {
+	ctx := stream.Context()
+	filter := fromProtoFilter(req.GetFilter())
+	afterID := 0
+	for {
+		articles := s.repo.ArticlesAfter(ctx, afterID, streamBatchSize)
+		if len(articles) == 0 {
+			return nil
+		}
+		for _, article := range articles {
+			if !filter.Matches(article) {
+				continue
+			}
+			if err := stream.Send(toProto(article)); err != nil {
+				return err
+			}
+		}
+		afterID = articles[len(articles)-1].ID
+		if err := ctx.Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+	}
+}
+
+func toProto(article models.Article) *articlepb.Article {
+	return &articlepb.Article{
+		Id:      int64(article.ID),
+		Title:   article.Title,
+		Content: article.Content,
+		Author:  article.Author,
+		Tags:    article.Tags,
+	}
+}
+
+func toProtoList(articles []models.Article) []*articlepb.Article {
+	result := make([]*articlepb.Article, len(articles))
+	for i, article := range articles {
+		result[i] = toProto(article)
+	}
+	return result
+}
+
+func fromProtoFilter(filter *articlepb.ArticleFilter) repositories.ArticleFilter {
+	return repositories.ArticleFilter{Tag: filter.GetTag(), Author: filter.GetAuthor()}
+}
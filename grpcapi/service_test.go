@@ -0,0 +1,164 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/brothergiez/restful-api/articlepb"
+	"github.com/brothergiez/restful-api/models"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startServer serves s in memory and returns a client connected to it.
+// startServer serves s in memory and returns a client that connects with
+// creds, or in cleartext when creds is nil.
+func startServer(t *testing.T, s *Server, creds ...credentials.TransportCredentials) articlepb.ArticleServiceClient {
+	listener := bufconn.Listen(1 << 20)
+	go s.Serve(listener)
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+
+	transport := insecure.NewCredentials()
+	if len(creds) > 0 {
+		transport = creds[0]
+	}
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(transport))
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return articlepb.NewArticleServiceClient(conn)
+}
+
+func setupService(t *testing.T) (articlepb.ArticleServiceClient, *repositories.ArticleRepository) {
+	repo := repositories.NewArticleRepository()
+	return startServer(t, NewServer(NewArticleService(repo), Config{})), repo
+}
+
+func TestArticleServiceCreateUpdateGet(t *testing.T) {
+	client, repo := setupService(t)
+	ctx := context.Background()
+
+	created, err := client.CreateArticle(ctx, &articlepb.CreateArticleRequest{
+		Title: "Learn Go", Content: "Go is fun", Author: "gopher", Tags: []string{"go"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), created.GetId())
+	assert.Equal(t, []string{"go"}, created.GetTags())
+
+	updated, err := client.UpdateArticle(ctx, &articlepb.UpdateArticleRequest{Id: 1, Title: "Learn Go", Content: "Go is great"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Go is great", updated.GetContent())
+	// The author and tags are not set, so they are kept.
+	assert.Equal(t, "gopher", updated.GetAuthor())
+	assert.Equal(t, []string{"go"}, updated.GetTags())
+	stored, _ := repo.GetArticle(ctx, 1)
+	assert.Equal(t, "Go is great", stored.Content)
+
+	noAuthor := ""
+	updated, err = client.UpdateArticle(ctx, &articlepb.UpdateArticleRequest{
+		Id: 1, Title: "Learn Go", Content: "Go is great", Author: &noAuthor, ClearTags: true,
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, updated.GetAuthor())
+	assert.Empty(t, updated.GetTags())
+
+	got, err := client.GetArticle(ctx, &articlepb.GetArticleRequest{Id: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "Go is great", got.GetContent())
+
+	_, err = client.UpdateArticle(ctx, &articlepb.UpdateArticleRequest{Id: 1, Title: "Only a title"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, "Invalid input: Title and Content are required", status.Convert(err).Message())
+
+	_, err = client.UpdateArticle(ctx, &articlepb.UpdateArticleRequest{Id: 9, Title: "A", Content: "B"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.GetArticle(ctx, &articlepb.GetArticleRequest{Id: 9})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func seedArticles(t *testing.T, repo *repositories.ArticleRepository, n int) {
+	for i := 1; i <= n; i++ {
+		draft := models.Article{Title: "Article", Content: "Content", Author: "alice"}
+		if i%3 == 0 {
+			draft.Title = "Go article"
+			draft.Tags = []string{"go"}
+		}
+		_, err := repo.CreateArticle(context.Background(), draft)
+		assert.NoError(t, err)
+	}
+}
+
+func TestArticleServiceListAndSearch(t *testing.T) {
+	client, repo := setupService(t)
+	ctx := context.Background()
+	seedArticles(t, repo, 12)
+
+	page, err := client.ListArticles(ctx, &articlepb.ListArticlesRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, page.GetArticles(), 10)
+	assert.Equal(t, int32(1), page.GetPage())
+	assert.Equal(t, int32(10), page.GetLimit())
+	assert.Equal(t, int32(12), page.GetTotal())
+	assert.Equal(t, int32(2), page.GetTotalPages())
+
+	page, err = client.ListArticles(ctx, &articlepb.ListArticlesRequest{Page: 2, Limit: 3, Filter: &articlepb.ArticleFilter{Tag: "GO"}})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), page.GetTotal())
+	assert.Len(t, page.GetArticles(), 1)
+	assert.Equal(t, int64(12), page.GetArticles()[0].GetId())
+
+	_, err = client.ListArticles(ctx, &articlepb.ListArticlesRequest{Page: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.ListArticles(ctx, &articlepb.ListArticlesRequest{Limit: -5})
+	assert.Equal(t, "Invalid limit number", status.Convert(err).Message())
+
+	found, err := client.SearchArticles(ctx, &articlepb.SearchArticlesRequest{Keyword: "go"})
+	assert.NoError(t, err)
+	assert.Len(t, found.GetArticles(), 4)
+}
+
+func TestArticleServiceStreamArticles(t *testing.T) {
+	client, repo := setupService(t)
+	// More than one batch.
+	seedArticles(t, repo, 2*streamBatchSize+5)
+
+	receive := func(filter *articlepb.ArticleFilter) []int64 {
+		stream, err := client.StreamArticles(context.Background(), &articlepb.StreamArticlesRequest{Filter: filter})
+		assert.NoError(t, err)
+		var ids []int64
+		for {
+			article, err := stream.Recv()
+			if err == io.EOF {
+				return ids
+			}
+			if !assert.NoError(t, err) {
+				return ids
+			}
+			ids = append(ids, article.GetId())
+		}
+	}
+
+	ids := receive(nil)
+	assert.Len(t, ids, 2*streamBatchSize+5)
+	assert.Equal(t, int64(1), ids[0])
+	assert.Equal(t, int64(2*streamBatchSize+5), ids[len(ids)-1])
+
+	ids = receive(&articlepb.ArticleFilter{Tag: "go", Author: "ALICE"})
+	assert.Len(t, ids, (2*streamBatchSize+5)/3)
+	for _, id := range ids {
+		assert.Zero(t, id%3)
+	}
+
+	assert.Empty(t, receive(&articlepb.ArticleFilter{Author: "bob"}))
+}
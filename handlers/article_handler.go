@@ -1,94 +1,223 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/models"
+	"github.com/brothergiez/restful-api/presence"
 	"github.com/brothergiez/restful-api/repositories"
+	"github.com/brothergiez/restful-api/requestid"
+	"github.com/brothergiez/restful-api/tracing"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// DefaultMaxBulkOperations is the largest batch BulkArticlesHandler accepts
+// unless WithMaxBulkOperations says otherwise.
+const DefaultMaxBulkOperations = 1000
+
 type ArticleHandler struct {
-	Repo *repositories.ArticleRepository
+	Repo              *repositories.ArticleRepository
+	maxBulkOperations int
+	stream            *events.Stream
+	heartbeat         time.Duration
+	presence          *presence.Hub
+	upgrader          websocket.Upgrader
+}
+
+type ArticleHandlerOption func(*ArticleHandler)
+
+// WithMaxBulkOperations limits the number of operations in one bulk request.
+func WithMaxBulkOperations(n int) ArticleHandlerOption {
+	return func(h *ArticleHandler) {
+		h.maxBulkOperations = n
+	}
 }
 
-func NewArticleHandler(repo *repositories.ArticleRepository) *ArticleHandler {
-	return &ArticleHandler{
-		Repo: repo,
+func NewArticleHandler(repo *repositories.ArticleRepository, opts ...ArticleHandlerOption) *ArticleHandler {
+	h := &ArticleHandler{
+		Repo:              repo,
+		maxBulkOperations: DefaultMaxBulkOperations,
+		heartbeat:         DefaultStreamHeartbeat,
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
+}
+
+// startSpan starts a span for a handler method as a child of the request span.
+// The returned context is passed on to the repository so its spans nest under
+// the handler's.
+func startSpan(c *gin.Context, method string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(c.Request.Context(), "ArticleHandler."+method)
+}
+
+// inputError is a validation failure whose message is shown to the client as
+// it is. The REST handlers and the GraphQL resolvers share these rules.
+type inputError string
+
+func (e inputError) Error() string {
+	return string(e)
+}
+
+const (
+	errTitleContentRequired inputError = "Invalid input: Title and Content are required"
+	errInvalidPage          inputError = "Invalid page number"
+	errInvalidLimit         inputError = "Invalid limit number"
+)
+
+// articleInput is the writable part of an article as clients send it. The
+// author and tags are pointers so that an update can tell a field that was
+// left out, which keeps the current value, from an empty one.
+type articleInput struct {
+	Title   string    `json:"title"`
+	Content string    `json:"content"`
+	Author  *string   `json:"author"`
+	Tags    *[]string `json:"tags"`
+}
+
+func (in articleInput) article() models.Article {
+	article := models.Article{Title: in.Title, Content: in.Content}
+	if in.Author != nil {
+		article.Author = *in.Author
+	}
+	if in.Tags != nil {
+		article.Tags = *in.Tags
+	}
+	return article
+}
+
+func (in articleInput) changes() repositories.ArticleChanges {
+	return repositories.ArticleChanges{Title: in.Title, Content: in.Content, Author: in.Author, Tags: in.Tags}
+}
+
+// checkUpdate requires the title and content, which an update replaces.
+func (in articleInput) checkUpdate() error {
+	if in.Title == "" || in.Content == "" {
+		return errTitleContentRequired
+	}
+	return nil
+}
+
+func checkPagination(page, limit int) error {
+	if page < 1 {
+		return errInvalidPage
+	}
+	if limit < 1 {
+		return errInvalidLimit
+	}
+	return nil
 }
 
 func (h *ArticleHandler) CreateArticleHandler(c *gin.Context) {
-	var input struct {
-		Title   string `json:"title"`
-		Content string `json:"content"`
+	ctx, span := startSpan(c, "CreateArticleHandler")
+	defer span.End()
+
+	format, ok := negotiate(c, itemFormats)
+	if !ok {
+		return
 	}
 
+	var input articleInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Input"})
+		c.JSON(http.StatusBadRequest, requestid.ErrorBody(c, "Invalid Input"))
 		return
 	}
 
-	article := h.Repo.CreateArticle(input.Title, input.Content)
-	c.JSON(http.StatusCreated, article)
+	article, err := h.Repo.CreateArticle(ctx, input.article())
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, requestid.ErrorBody(c, "Failed to store article"))
+		return
+	}
+	respond(c, format, http.StatusCreated, article)
 }
 
 func (h *ArticleHandler) UpdateArticleHandler(c *gin.Context) {
+	ctx, span := startSpan(c, "UpdateArticleHandler")
+	defer span.End()
+
+	format, ok := negotiate(c, itemFormats)
+	if !ok {
+		return
+	}
+
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		c.JSON(http.StatusBadRequest, requestid.ErrorBody(c, "Invalid ID"))
 		return
 	}
 
-	var input struct {
-		Title   string `json:"title" binding:"required"`
-		Content string `json:"content" binding:"required"`
+	var input articleInput
+	if err := c.ShouldBindJSON(&input); err == nil {
+		err = input.checkUpdate()
 	}
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: Title and Content are required"})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, requestid.ErrorBody(c, errTitleContentRequired.Error()))
 		return
 	}
 
-	article, err := h.Repo.UpdateArticle(id, input.Title, input.Content)
+	article, err := h.Repo.UpdateArticle(ctx, id, input.changes())
+	if errors.Is(err, repositories.ErrNotFound) {
+		c.JSON(http.StatusNotFound, requestid.ErrorBody(c, "Article not found"))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, requestid.ErrorBody(c, "Failed to store article"))
 		return
 	}
 
-	c.JSON(http.StatusOK, article)
+	respond(c, format, http.StatusOK, article)
 }
 
 func (h *ArticleHandler) SearchArticlesHandler(c *gin.Context) {
+	ctx, span := startSpan(c, "SearchArticlesHandler")
+	defer span.End()
+
+	format, ok := negotiate(c, listFormats)
+	if !ok {
+		return
+	}
+
 	keyword := c.Query("keyword")
-	articles := h.Repo.SearchArticles(keyword)
-	c.JSON(http.StatusOK, articles)
+	articles := h.Repo.SearchArticles(ctx, keyword)
+	respond(c, format, http.StatusOK, articles)
 }
 
 func (h *ArticleHandler) GetAllArticlesHandler(c *gin.Context) {
-	pageStr := c.DefaultQuery("page", "1")
-	limitStr := c.DefaultQuery("limit", "10")
+	ctx, span := startSpan(c, "GetAllArticlesHandler")
+	defer span.End()
 
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+	format, ok := negotiate(c, listFormats)
+	if !ok {
 		return
 	}
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 1 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
+	// Unparsable values are as invalid as zero.
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err := checkPagination(page, limit); err != nil {
+		c.JSON(http.StatusBadRequest, requestid.ErrorBody(c, err.Error()))
 		return
 	}
 
-	articles, total := h.Repo.GetAllArticlesWithPagination(page, limit)
+	articles, total := h.Repo.GetAllArticlesWithPagination(ctx, page, limit)
 
 	totalPages := (total + limit - 1) / limit
 
-	c.JSON(http.StatusOK, gin.H{
-		"page":       page,
-		"limit":      limit,
-		"total":      total,
-		"totalPages": totalPages,
-		"articles":   articles,
+	respond(c, format, http.StatusOK, articlePage{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: totalPages,
+		Articles:   articles,
 	})
 }
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -40,7 +41,7 @@ func TestCreateArticleHandler(t *testing.T) {
 
 func TestUpdateArticleHandler(t *testing.T) {
 	repo := repositories.NewArticleRepository()
-	article := repo.CreateArticle("Original Title", "Original Content")
+	article, _ := repo.CreateArticle(context.Background(), models.Article{Title: "Original Title", Content: "Original Content"})
 	handler := NewArticleHandler(repo)
 
 	router := gin.Default()
@@ -68,10 +69,40 @@ func TestUpdateArticleHandler(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, resp.Code)
 }
 
+func TestUpdateArticleHandlerKeepsOmittedFields(t *testing.T) {
+	repo := repositories.NewArticleRepository()
+	article, _ := repo.CreateArticle(context.Background(), models.Article{Title: "Go", Content: "One", Author: "gopher", Tags: []string{"go", "intro"}})
+	handler := NewArticleHandler(repo)
+
+	router := gin.Default()
+	router.PUT("/articles/:id", handler.UpdateArticleHandler)
+	url := "/articles/" + strconv.Itoa(article.ID)
+
+	for _, tc := range []struct {
+		payload string
+		want    models.Article
+	}{
+		{`{"title":"Go v2","content":"Two"}`,
+			models.Article{ID: article.ID, Title: "Go v2", Content: "Two", Author: "gopher", Tags: []string{"go", "intro"}}},
+		{`{"title":"Go v3","content":"Three","author":"alice","tags":null}`,
+			models.Article{ID: article.ID, Title: "Go v3", Content: "Three", Author: "alice", Tags: []string{"go", "intro"}}},
+		{`{"title":"Go v4","content":"Four","author":"","tags":[]}`,
+			models.Article{ID: article.ID, Title: "Go v4", Content: "Four", Tags: []string{}}},
+	} {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodPut, url, bytes.NewBufferString(tc.payload)))
+		assert.Equal(t, http.StatusOK, resp.Code, tc.payload)
+
+		stored, err := repo.GetArticle(context.Background(), article.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, stored, tc.payload)
+	}
+}
+
 func TestSearchArticlesHandler(t *testing.T) {
 	repo := repositories.NewArticleRepository()
-	repo.CreateArticle("First Article", "Content of the first article")
-	repo.CreateArticle("Second Article", "Content of the second article")
+	repo.CreateArticle(context.Background(), models.Article{Title: "First Article", Content: "Content of the first article"})
+	repo.CreateArticle(context.Background(), models.Article{Title: "Second Article", Content: "Content of the second article"})
 	handler := NewArticleHandler(repo)
 
 	router := gin.Default()
@@ -95,7 +126,7 @@ func TestSearchArticlesHandler(t *testing.T) {
 func TestGetAllArticlesHandler(t *testing.T) {
 	repo := repositories.NewArticleRepository()
 	for i := 1; i <= 15; i++ {
-		repo.CreateArticle("Title "+strconv.Itoa(i), "Content "+strconv.Itoa(i))
+		repo.CreateArticle(context.Background(), models.Article{Title: "Title " + strconv.Itoa(i), Content: "Content " + strconv.Itoa(i)})
 	}
 	handler := NewArticleHandler(repo)
 
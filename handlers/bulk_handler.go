@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/brothergiez/restful-api/models"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/brothergiez/restful-api/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+// bulkOperation is one operation as clients send it. As in articleInput,
+// an update keeps the author and tags when they are left out.
+type bulkOperation struct {
+	Action  string    `json:"action"`
+	ID      int       `json:"id"`
+	Title   string    `json:"title"`
+	Content string    `json:"content"`
+	Author  *string   `json:"author"`
+	Tags    *[]string `json:"tags"`
+}
+
+type bulkRequest struct {
+	Atomic     bool            `json:"atomic"`
+	Operations []bulkOperation `json:"operations"`
+}
+
+type bulkItemResult struct {
+	Index   int             `json:"index" xml:"index"`
+	Action  string          `json:"action" xml:"action"`
+	Status  int             `json:"status" xml:"status"`
+	Article *models.Article `json:"article,omitempty" xml:"article,omitempty"`
+	Error   string          `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+type bulkResponse struct {
+	XMLName   xml.Name         `json:"-" xml:"bulkResult"`
+	Atomic    bool             `json:"atomic" xml:"atomic"`
+	Committed bool             `json:"committed" xml:"committed"`
+	Succeeded int              `json:"succeeded" xml:"succeeded"`
+	Failed    int              `json:"failed" xml:"failed"`
+	Results   []bulkItemResult `json:"results" xml:"results>result"`
+}
+
+// bulkItemStatus maps the outcome of one operation to the status code the
+// equivalent single request would have returned.
+func bulkItemStatus(action repositories.BulkAction, err error) int {
+	switch {
+	case err == nil && action == repositories.BulkCreate:
+		return http.StatusCreated
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, repositories.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repositories.ErrRolledBack):
+		return http.StatusFailedDependency
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// BulkArticlesHandler applies a batch of create, update and delete
+// operations. The response is 200 when every operation succeeded, 207 when
+// some failed and 422 when an atomic batch was rolled back.
+func (h *ArticleHandler) BulkArticlesHandler(c *gin.Context) {
+	ctx, span := startSpan(c, "BulkArticlesHandler")
+	defer span.End()
+
+	format, ok := negotiate(c, itemFormats)
+	if !ok {
+		return
+	}
+
+	var input bulkRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, requestid.ErrorBody(c, "Invalid Input"))
+		return
+	}
+	if len(input.Operations) == 0 {
+		c.JSON(http.StatusBadRequest, requestid.ErrorBody(c, "Invalid input: operations must not be empty"))
+		return
+	}
+	if len(input.Operations) > h.maxBulkOperations {
+		c.JSON(http.StatusRequestEntityTooLarge, requestid.ErrorBody(c,
+			fmt.Sprintf("Too many operations: at most %d are allowed per request", h.maxBulkOperations)))
+		return
+	}
+
+	ops := make([]repositories.BulkOperation, len(input.Operations))
+	for i, op := range input.Operations {
+		ops[i] = repositories.BulkOperation{
+			Action:  repositories.BulkAction(op.Action),
+			ID:      op.ID,
+			Title:   op.Title,
+			Content: op.Content,
+			Author:  op.Author,
+			Tags:    op.Tags,
+		}
+	}
+
+	results, committed, err := h.Repo.BulkWrite(ctx, ops, input.Atomic)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, requestid.ErrorBody(c, "Failed to store articles"))
+		return
+	}
+
+	response := bulkResponse{
+		Atomic:    input.Atomic,
+		Committed: committed,
+		Results:   make([]bulkItemResult, len(results)),
+	}
+	for i, result := range results {
+		item := bulkItemResult{
+			Index:  i,
+			Action: input.Operations[i].Action,
+			Status: bulkItemStatus(ops[i].Action, result.Err),
+		}
+		if result.Err != nil {
+			item.Error = result.Err.Error()
+			response.Failed++
+		} else {
+			article := result.Article
+			item.Article = &article
+			response.Succeeded++
+		}
+		response.Results[i] = item
+	}
+
+	status := http.StatusOK
+	switch {
+	case !committed:
+		status = http.StatusUnprocessableEntity
+	case response.Failed > 0:
+		status = http.StatusMultiStatus
+	}
+	respond(c, format, status, response)
+}
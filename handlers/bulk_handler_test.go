@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brothergiez/restful-api/models"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func postBulk(router *gin.Engine, payload string) (*httptest.ResponseRecorder, bulkResponse) {
+	req := httptest.NewRequest(http.MethodPost, "/articles/bulk", bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var result bulkResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+	return resp, result
+}
+
+func setupBulkRouter(repo *repositories.ArticleRepository, opts ...ArticleHandlerOption) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/articles/bulk", NewArticleHandler(repo, opts...).BulkArticlesHandler)
+	return router
+}
+
+func TestBulkArticlesHandler(t *testing.T) {
+	repo := repositories.NewArticleRepository()
+	repo.CreateArticle(context.Background(), models.Article{Title: "First", Content: "One"})
+	router := setupBulkRouter(repo)
+
+	resp, result := postBulk(router, `{"operations":[
+		{"action":"create","title":"Second","content":"Two"},
+		{"action":"update","id":1,"title":"First v2","content":"One again"},
+		{"action":"delete","id":42},
+		{"action":"publish","id":1}
+	]}`)
+
+	assert.Equal(t, http.StatusMultiStatus, resp.Code)
+	assert.True(t, result.Committed)
+	assert.Equal(t, 2, result.Succeeded)
+	assert.Equal(t, 2, result.Failed)
+
+	assert.Equal(t, http.StatusCreated, result.Results[0].Status)
+	assert.Equal(t, 2, result.Results[0].Article.ID)
+	assert.Equal(t, http.StatusOK, result.Results[1].Status)
+	assert.Equal(t, "First v2", result.Results[1].Article.Title)
+	assert.Equal(t, http.StatusNotFound, result.Results[2].Status)
+	assert.Equal(t, "article not found", result.Results[2].Error)
+	assert.Equal(t, http.StatusBadRequest, result.Results[3].Status)
+	assert.Equal(t, "publish", result.Results[3].Action)
+	assert.Equal(t, 2, repo.CountArticles(context.Background()))
+
+	resp, result = postBulk(router, `{"operations":[{"action":"delete","id":2}]}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "Second", result.Results[0].Article.Title)
+	assert.Equal(t, 1, repo.CountArticles(context.Background()))
+}
+
+func TestBulkArticlesHandlerAtomic(t *testing.T) {
+	repo := repositories.NewArticleRepository()
+	router := setupBulkRouter(repo)
+
+	resp, result := postBulk(router, `{"atomic":true,"operations":[
+		{"action":"create","title":"First","content":"One"},
+		{"action":"update","id":7,"title":"Missing","content":"Missing"}
+	]}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.False(t, result.Committed)
+	assert.Equal(t, http.StatusFailedDependency, result.Results[0].Status)
+	assert.Nil(t, result.Results[0].Article)
+	assert.Equal(t, http.StatusNotFound, result.Results[1].Status)
+	assert.Equal(t, 0, repo.CountArticles(context.Background()))
+}
+
+func TestBulkArticlesHandlerLimits(t *testing.T) {
+	repo := repositories.NewArticleRepository()
+	router := setupBulkRouter(repo, WithMaxBulkOperations(2))
+
+	ops := strings.Repeat(`{"action":"create","title":"T","content":"C"},`, 3)
+	resp, _ := postBulk(router, `{"operations":[`+strings.TrimSuffix(ops, ",")+`]}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+	assert.Contains(t, resp.Body.String(), "at most 2")
+
+	resp, _ = postBulk(router, `{"operations":[]}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp, _ = postBulk(router, `[{"action":"create"}]`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, 0, repo.CountArticles(context.Background()))
+}
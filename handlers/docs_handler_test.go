@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocsHandler(t *testing.T) {
+	handler, err := NewDocsHandler()
+	if !assert.NoError(t, err) {
+		return
+	}
+	router := gin.Default()
+	router.GET("/openapi.json", handler.OpenAPIHandler)
+	router.GET("/docs", handler.SwaggerUIHandler)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, mimeJSON, resp.Header().Get("Content-Type"))
+	var doc struct {
+		OpenAPI string         `json:"openapi"`
+		Paths   map[string]any `json:"paths"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/articles/update/{id}")
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Header().Get("Content-Type"), "text/html")
+	assert.NotContains(t, resp.Body.String(), "https://")
+}
+
+func TestDocsHandlerSwaggerUI(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		assets fstest.MapFS
+		page   string
+		status int
+	}{
+		{"bundled", fstest.MapFS{
+			"swagger-ui.css":       {Data: []byte("body {}")},
+			"swagger-ui-bundle.js": {Data: []byte("var SwaggerUIBundle;")},
+			"VERSION":              {Data: []byte("5.17.14\n")},
+		}, `<script src="docs/swagger-ui-bundle.js"></script>`, http.StatusOK},
+		{"missing", fstest.MapFS{}, `<a href="openapi.json">`, http.StatusNotFound},
+	} {
+		handler, err := newDocsHandler(tc.assets)
+		if !assert.NoError(t, err) {
+			return
+		}
+		router := gin.New()
+		router.GET("/docs", handler.SwaggerUIHandler)
+		router.GET("/docs/*filepath", handler.SwaggerUIAssetHandler)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/docs", nil))
+		assert.Contains(t, resp.Body.String(), tc.page, tc.name)
+		policy := resp.Header().Get("Content-Security-Policy")
+		assert.Contains(t, policy, "script-src 'self' 'sha256-", tc.name)
+		assert.Contains(t, policy, "connect-src 'self'", tc.name)
+
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/docs/swagger-ui-bundle.js", nil))
+		assert.Equal(t, tc.status, resp.Code, tc.name)
+		if tc.status == http.StatusOK {
+			assert.Equal(t, "var SwaggerUIBundle;", resp.Body.String())
+			assert.Contains(t, resp.Header().Get("Content-Type"), "javascript")
+		}
+
+		// Only the files the page loads are served.
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/docs/VERSION", nil))
+		assert.Equal(t, http.StatusNotFound, resp.Code, tc.name)
+	}
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/brothergiez/restful-api/tracing"
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// GraphQLConfig bounds the queries GraphQLHandler runs. Every selected field
+// costs one towards MaxComplexity, and the fields below one with a limit
+// argument count once per item it may return.
+type GraphQLConfig struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+func DefaultGraphQLConfig() GraphQLConfig {
+	return GraphQLConfig{MaxDepth: 6, MaxComplexity: 1000}
+}
+
+// GraphQLHandler serves the article queries and mutations over GraphQL.
+type GraphQLHandler struct {
+	schema graphql.Schema
+	config GraphQLConfig
+}
+
+func NewGraphQLHandler(repo *repositories.ArticleRepository, config GraphQLConfig) (*GraphQLHandler, error) {
+	schema, err := newArticleSchema(repo)
+	if err != nil {
+		return nil, fmt.Errorf("build graphql schema: %w", err)
+	}
+	return &GraphQLHandler{schema: schema, config: config}, nil
+}
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// ServeGraphQL runs a query sent as JSON in a POST body, or in the query,
+// operationName and variables parameters of a GET, which may not mutate.
+// Requests that cannot run are answered with 400; errors of individual
+// fields are reported next to the data with 200.
+func (h *GraphQLHandler) ServeGraphQL(c *gin.Context) {
+	ctx, span := tracing.Tracer().Start(c.Request.Context(), "GraphQLHandler.ServeGraphQL")
+	defer span.End()
+
+	var request graphQLRequest
+	if c.Request.Method == http.MethodGet {
+		request.Query = c.Query("query")
+		request.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				graphQLFailure(c, http.StatusBadRequest, gqlerrors.NewFormattedError("variables must be a JSON object"))
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&request); err != nil {
+		graphQLFailure(c, http.StatusBadRequest, gqlerrors.NewFormattedError("Invalid Input"))
+		return
+	}
+	if strings.TrimSpace(request.Query) == "" {
+		graphQLFailure(c, http.StatusBadRequest, gqlerrors.NewFormattedError("query is required"))
+		return
+	}
+
+	document, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(request.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		graphQLFailure(c, http.StatusBadRequest, gqlerrors.FormatError(err))
+		return
+	}
+	if result := graphql.ValidateDocument(&h.schema, document, nil); !result.IsValid {
+		graphQLFailure(c, http.StatusBadRequest, result.Errors...)
+		return
+	}
+
+	operation := findOperation(document, request.OperationName)
+	if operation == nil {
+		graphQLFailure(c, http.StatusBadRequest, gqlerrors.NewFormattedError("operationName does not name an operation of the document"))
+		return
+	}
+	if c.Request.Method == http.MethodGet && operation.Operation != ast.OperationTypeQuery {
+		c.Header("Allow", http.MethodPost)
+		graphQLFailure(c, http.StatusMethodNotAllowed, gqlerrors.NewFormattedError("mutations must be sent with POST"))
+		return
+	}
+	if err := h.checkLimits(document, operation, request.Variables); err != nil {
+		graphQLFailure(c, http.StatusBadRequest, *err)
+		return
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           document,
+		OperationName: request.OperationName,
+		Args:          request.Variables,
+		Context:       ctx,
+	})
+	for _, formatted := range result.Errors {
+		var stored storeError
+		if located, ok := formatted.OriginalError().(*gqlerrors.Error); ok && errors.As(located.OriginalError, &stored) {
+			_ = c.Error(stored.cause)
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func graphQLFailure(c *gin.Context, status int, errs ...gqlerrors.FormattedError) {
+	c.JSON(status, &graphql.Result{Errors: errs})
+}
+
+// findOperation returns the operation to run: the one named name, or the
+// only one when name is empty.
+func findOperation(document *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = operation
+		} else if operation.Name != nil && operation.Name.Value == name {
+			return operation
+		}
+	}
+	return found
+}
+
+// listDefaults are the default limits of the Query fields that return lists.
+var listDefaults = map[string]int{
+	"articles": defaultGraphQLPageLimit,
+	"search":   defaultGraphQLSearchLimit,
+}
+
+// queryCost measures an operation for the depth and complexity limits. The
+// document has been validated, so its fragments do not form cycles.
+type queryCost struct {
+	fragments map[string]*ast.FragmentDefinition
+	defaults  map[string]ast.Value
+	variables map[string]interface{}
+}
+
+func (h *GraphQLHandler) checkLimits(document *ast.Document, operation *ast.OperationDefinition, variables map[string]interface{}) *gqlerrors.FormattedError {
+	cost := queryCost{
+		fragments: map[string]*ast.FragmentDefinition{},
+		defaults:  map[string]ast.Value{},
+		variables: variables,
+	}
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			cost.fragments[fragment.Name.Value] = fragment
+		}
+	}
+	for _, definition := range operation.VariableDefinitions {
+		cost.defaults[definition.Variable.Name.Value] = definition.DefaultValue
+	}
+
+	depth, complexity := cost.selections(operation.SelectionSet, true)
+	if depth > h.config.MaxDepth {
+		return limitError(fmt.Sprintf("query depth %d exceeds the limit of %d", depth, h.config.MaxDepth), "QUERY_TOO_DEEP")
+	}
+	if complexity > h.config.MaxComplexity {
+		return limitError(fmt.Sprintf("query complexity %d exceeds the limit of %d", complexity, h.config.MaxComplexity), "QUERY_TOO_COMPLEX")
+	}
+	return nil
+}
+
+func limitError(message, code string) *gqlerrors.FormattedError {
+	err := gqlerrors.NewFormattedError(message)
+	err.Extensions = map[string]interface{}{"code": code}
+	return &err
+}
+
+// selections returns the depth and complexity of a selection set, expanding
+// fragments in place. __typename is free; __schema and __type are counted
+// like any other field. Only the top level of the operation has list
+// arguments.
+func (q queryCost) selections(set *ast.SelectionSet, top bool) (depth, complexity int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var d, c int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if selection.Name.Value == "__typename" {
+				continue
+			}
+			d, c = q.selections(selection.SelectionSet, false)
+			size := 1
+			if top {
+				size = q.listSize(selection)
+			}
+			d, c = d+1, 1+size*c
+		case *ast.InlineFragment:
+			d, c = q.selections(selection.SelectionSet, top)
+		case *ast.FragmentSpread:
+			if fragment, ok := q.fragments[selection.Name.Value]; ok {
+				d, c = q.selections(fragment.SelectionSet, top)
+			}
+		}
+		depth = max(depth, d)
+		complexity += c
+	}
+	return depth, complexity
+}
+
+// listSize is the number of items a field may return: its limit argument,
+// or the default limit of a list field, or one.
+func (q queryCost) listSize(field *ast.Field) int {
+	size, isList := listDefaults[field.Name.Value]
+	if !isList {
+		return 1
+	}
+	for _, argument := range field.Arguments {
+		if argument.Name.Value == "limit" {
+			if limit, ok := q.intValue(argument.Value); ok {
+				size = limit
+			}
+		}
+	}
+	return max(size, 1)
+}
+
+func (q queryCost) intValue(value ast.Value) (int, bool) {
+	switch value := value.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(value.Value)
+		return n, err == nil
+	case *ast.Variable:
+		name := value.Name.Value
+		if v, ok := q.variables[name]; ok {
+			// Variables are decoded from JSON. Values beyond Int fail
+			// coercion later but must not overflow the estimate.
+			n, ok := v.(float64)
+			return int(min(n, math.MaxInt32)), ok
+		}
+		if def := q.defaults[name]; def != nil {
+			return q.intValue(def)
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/brothergiez/restful-api/models"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupGraphQLRouter(t *testing.T, config GraphQLConfig) (*gin.Engine, *repositories.ArticleRepository) {
+	repo := repositories.NewArticleRepository()
+	handler, err := NewGraphQLHandler(repo, config)
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/graphql", handler.ServeGraphQL)
+	router.POST("/graphql", handler.ServeGraphQL)
+	return router, repo
+}
+
+type graphQLResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func postGraphQL(t *testing.T, router *gin.Engine, query string, variables map[string]interface{}) (int, graphQLResponse) {
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	resp := sendJSON(router, http.MethodPost, "/graphql", string(body))
+	var decoded graphQLResponse
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &decoded), resp.Body.String())
+	return resp.Code, decoded
+}
+
+func TestGraphQLQueries(t *testing.T) {
+	router, repo := setupGraphQLRouter(t, DefaultGraphQLConfig())
+	for _, draft := range []models.Article{
+		{Title: "Go generics", Content: "Type parameters", Author: "alice", Tags: []string{"go"}},
+		{Title: "Rust traits", Content: "Like Go interfaces", Author: "bob"},
+		{Title: "Go modules", Content: "Versioning", Author: "bob", Tags: []string{"Go", "tooling"}},
+	} {
+		_, err := repo.CreateArticle(context.Background(), draft)
+		assert.NoError(t, err)
+	}
+
+	status, resp := postGraphQL(t, router, `{ article(id: 2) { id title author tags } missing: article(id: 9) { id } }`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"id":2,"title":"Rust traits","author":"bob","tags":[]}`, string(resp.Data["article"]))
+	assert.JSONEq(t, `null`, string(resp.Data["missing"]))
+
+	_, resp = postGraphQL(t, router, `query List($tag: String) {
+		articles(limit: 1, tag: $tag) { page limit total totalPages articles { id } }
+	}`, map[string]interface{}{"tag": "GO"})
+	assert.JSONEq(t, `{"page":1,"limit":1,"total":2,"totalPages":2,"articles":[{"id":1}]}`, string(resp.Data["articles"]))
+
+	_, resp = postGraphQL(t, router, `{ articles(page: 2, author: "bob") { total articles { id } } }`, nil)
+	assert.JSONEq(t, `{"total":2,"articles":[]}`, string(resp.Data["articles"]))
+
+	_, resp = postGraphQL(t, router, `{ search(keyword: "go", limit: 2) { id } }`, nil)
+	assert.JSONEq(t, `[{"id":1},{"id":2}]`, string(resp.Data["search"]))
+
+	// The pagination rules of get-all apply.
+	status, resp = postGraphQL(t, router, `{ articles(page: 0) { total } }`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Invalid page number", resp.Errors[0].Message)
+	assert.Equal(t, "BAD_USER_INPUT", resp.Errors[0].Extensions["code"])
+
+	// Queries may also be sent with GET.
+	req := httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(`{ article(id: 1) { title } }`), nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":{"article":{"title":"Go generics"}}}`, rec.Body.String())
+}
+
+func TestGraphQLMutations(t *testing.T) {
+	router, repo := setupGraphQLRouter(t, DefaultGraphQLConfig())
+
+	status, resp := postGraphQL(t, router, `mutation Create($input: ArticleInput!) {
+		createArticle(input: $input) { id title content author tags }
+	}`, map[string]interface{}{"input": map[string]interface{}{"title": "Hello", "content": "World", "tags": []string{"intro"}}})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"id":1,"title":"Hello","content":"World","author":null,"tags":["intro"]}`, string(resp.Data["createArticle"]))
+
+	// Tags that are left out are kept.
+	_, resp = postGraphQL(t, router, `mutation { updateArticle(id: 1, input: {title: "Hi", content: "There", author: "alice"}) { title author tags } }`, nil)
+	assert.JSONEq(t, `{"title":"Hi","author":"alice","tags":["intro"]}`, string(resp.Data["updateArticle"]))
+	article, _ := repo.GetArticle(context.Background(), 1)
+	assert.Equal(t, "Hi", article.Title)
+
+	_, resp = postGraphQL(t, router, `mutation { updateArticle(id: 1, input: {title: "Hi", content: "There", tags: []}) { author tags } }`, nil)
+	assert.JSONEq(t, `{"author":"alice","tags":[]}`, string(resp.Data["updateArticle"]))
+
+	_, resp = postGraphQL(t, router, `mutation { updateArticle(id: 1, input: {title: "", content: "There"}) { id } }`, nil)
+	assert.Equal(t, "Invalid input: Title and Content are required", resp.Errors[0].Message)
+	assert.Equal(t, "BAD_USER_INPUT", resp.Errors[0].Extensions["code"])
+
+	_, resp = postGraphQL(t, router, `mutation { updateArticle(id: 7, input: {title: "A", content: "B"}) { id } }`, nil)
+	assert.Equal(t, "Article not found", resp.Errors[0].Message)
+	assert.Equal(t, "NOT_FOUND", resp.Errors[0].Extensions["code"])
+
+	// Mutations are not accepted over GET.
+	req := httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(`mutation { createArticle(input: {title: "A", content: "B"}) { id } }`), nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, 1, repo.CountArticles(context.Background()))
+}
+
+func TestGraphQLRejectsInvalidRequests(t *testing.T) {
+	router, _ := setupGraphQLRouter(t, DefaultGraphQLConfig())
+
+	for name, body := range map[string]string{
+		"not json":      `{"query":`,
+		"no query":      `{}`,
+		"syntax error":  `{"query":"{ article(id: 1) { id }"}`,
+		"unknown field": `{"query":"{ article(id: 1) { views } }"}`,
+		"wrong type":    `{"query":"{ article(id: \"one\") { id } }"}`,
+		"ambiguous":     `{"query":"query A { search(keyword: \"a\") { id } } query B { search(keyword: \"b\") { id } }"}`,
+	} {
+		resp := sendJSON(router, http.MethodPost, "/graphql", body)
+		assert.Equal(t, http.StatusBadRequest, resp.Code, name)
+		assert.Contains(t, resp.Body.String(), `"errors"`, name)
+	}
+}
+
+func TestGraphQLLimits(t *testing.T) {
+	router, _ := setupGraphQLRouter(t, GraphQLConfig{MaxDepth: 3, MaxComplexity: 100})
+
+	// 1 + 10 * (1 + 1 + 1) = 31
+	status, _ := postGraphQL(t, router, `{ articles { total articles { id } } }`, nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, resp := postGraphQL(t, router, `{ articles(limit: 50) { total articles { id title } } }`, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "query complexity 201 exceeds the limit of 100", resp.Errors[0].Message)
+	assert.Equal(t, "QUERY_TOO_COMPLEX", resp.Errors[0].Extensions["code"])
+
+	status, resp = postGraphQL(t, router, `query Search($n: Int = 2) { search(keyword: "go", limit: $n) { id } }`,
+		map[string]interface{}{"n": 200})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "QUERY_TOO_COMPLEX", resp.Errors[0].Extensions["code"])
+
+	status, _ = postGraphQL(t, router, `query Search($n: Int = 2) { search(keyword: "go", limit: $n) { id } }`, nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, resp = postGraphQL(t, router, `{ articles(limit: 1) { articles { id } } a: articles(limit: 1) { articles { ...Deeper } } }
+		fragment Deeper on Article { tags id }`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, resp.Errors)
+
+	// __typename is free; introspection subtrees are counted.
+	status, _ = postGraphQL(t, router, `{ __typename articles(limit: 1) { __typename articles { __typename id } } }`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = postGraphQL(t, router, `{ __type(name: "Article") { name fields { name } } }`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	status, resp = postGraphQL(t, router, `{ __schema { types { name fields { name type { name ofType { name } } } } } }`, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "query depth 6 exceeds the limit of 3", resp.Errors[0].Message)
+	assert.Equal(t, "QUERY_TOO_DEEP", resp.Errors[0].Extensions["code"])
+
+	// Fragments count like the fields they contain.
+	shallow, _ := setupGraphQLRouter(t, GraphQLConfig{MaxDepth: 2, MaxComplexity: 100})
+	document := `query Deep { articles { ...Page } }
+		fragment Page on ArticlePage { articles { ... on Article { id } } }
+		query Other { search(keyword: "x") { id } }`
+	for operation, want := range map[string]int{"Deep": http.StatusBadRequest, "Other": http.StatusOK} {
+		body, _ := json.Marshal(map[string]interface{}{"operationName": operation, "query": document})
+		rec := sendJSON(shallow, http.MethodPost, "/graphql", string(body))
+		assert.Equal(t, want, rec.Code, operation)
+		if want == http.StatusBadRequest {
+			assert.Contains(t, rec.Body.String(), `"message":"query depth 3 exceeds the limit of 2"`)
+			assert.Contains(t, rec.Body.String(), `"code":"QUERY_TOO_DEEP"`)
+		}
+	}
+}
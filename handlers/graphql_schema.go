@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/brothergiez/restful-api/models"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/graphql-go/graphql"
+)
+
+// Defaults of the list arguments, shared with the complexity estimate.
+const (
+	defaultGraphQLPageLimit   = 10
+	defaultGraphQLSearchLimit = 20
+)
+
+// graphQLError is a resolver error with a machine readable code in its
+// extensions, such as NOT_FOUND.
+type graphQLError struct {
+	message string
+	code    string
+}
+
+func (e graphQLError) Error() string {
+	return e.message
+}
+
+func (e graphQLError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+func (e inputError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": "BAD_USER_INPUT"}
+}
+
+// storeError hides why a write failed from the client. GraphQLHandler logs
+// the cause.
+type storeError struct {
+	cause error
+}
+
+func (e storeError) Error() string {
+	return "Failed to store article"
+}
+
+func (e storeError) Unwrap() error {
+	return e.cause
+}
+
+func (e storeError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": "INTERNAL_SERVER_ERROR"}
+}
+
+var errArticleNotFound = graphQLError{message: "Article not found", code: "NOT_FOUND"}
+
+// newArticleSchema builds the GraphQL schema over repo. The resolvers apply
+// the same rules as the REST handlers.
+func newArticleSchema(repo *repositories.ArticleRepository) (graphql.Schema, error) {
+	articleType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Article",
+		Fields: graphql.Fields{
+			"id":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"title":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"content": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"author": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if author := p.Source.(models.Article).Author; author != "" {
+						return author, nil
+					}
+					return nil, nil
+				},
+			},
+			"tags": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if tags := p.Source.(models.Article).Tags; tags != nil {
+						return tags, nil
+					}
+					return []string{}, nil
+				},
+			},
+		},
+	})
+	articleList := graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(articleType)))
+
+	pageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ArticlePage",
+		Fields: graphql.Fields{
+			"page":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"limit":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"total":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"totalPages": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"articles":   &graphql.Field{Type: articleList},
+		},
+	})
+
+	inputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ArticleInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"content": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"author": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "Kept by updateArticle when left out or null.",
+			},
+			"tags": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewList(graphql.NewNonNull(graphql.String)),
+				Description: "Kept by updateArticle when left out or null; an empty list removes every tag.",
+			},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"article": &graphql.Field{
+				Type:        articleType,
+				Description: "The article with the ID, or null.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					article, err := repo.GetArticle(p.Context, p.Args["id"].(int))
+					if errors.Is(err, repositories.ErrNotFound) {
+						return nil, nil
+					}
+					return article, err
+				},
+			},
+			"articles": &graphql.Field{
+				Type:        graphql.NewNonNull(pageType),
+				Description: "A page of the articles in ID order, optionally only those with a tag or author.",
+				Args: graphql.FieldConfigArgument{
+					"page":   &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+					"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultGraphQLPageLimit},
+					"tag":    &graphql.ArgumentConfig{Type: graphql.String},
+					"author": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					page, _ := p.Args["page"].(int)
+					limit, _ := p.Args["limit"].(int)
+					if err := checkPagination(page, limit); err != nil {
+						return nil, err
+					}
+					tag, _ := p.Args["tag"].(string)
+					author, _ := p.Args["author"].(string)
+					articles, total := repo.ListArticles(p.Context, repositories.ArticleFilter{Tag: tag, Author: author}, page, limit)
+					return articlePage{
+						Page:       page,
+						Limit:      limit,
+						Total:      total,
+						TotalPages: (total + limit - 1) / limit,
+						Articles:   articles,
+					}, nil
+				},
+			},
+			"search": &graphql.Field{
+				Type:        articleList,
+				Description: "The first articles whose title or content contains the keyword, ignoring case.",
+				Args: graphql.FieldConfigArgument{
+					"keyword": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"limit":   &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultGraphQLSearchLimit},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					limit, _ := p.Args["limit"].(int)
+					if err := checkPagination(1, limit); err != nil {
+						return nil, err
+					}
+					articles := repo.SearchArticles(p.Context, p.Args["keyword"].(string))
+					return articles[:min(limit, len(articles))], nil
+				},
+			},
+		},
+	})
+
+	inputArg := &graphql.ArgumentConfig{Type: graphql.NewNonNull(inputType)}
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createArticle": &graphql.Field{
+				Type: graphql.NewNonNull(articleType),
+				Args: graphql.FieldConfigArgument{"input": inputArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					article, err := repo.CreateArticle(p.Context, graphQLInput(p.Args["input"]).article())
+					if err != nil {
+						return nil, storeError{cause: err}
+					}
+					return article, nil
+				},
+			},
+			"updateArticle": &graphql.Field{
+				Type: graphql.NewNonNull(articleType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"input": inputArg,
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input := graphQLInput(p.Args["input"])
+					if err := input.checkUpdate(); err != nil {
+						return nil, err
+					}
+					article, err := repo.UpdateArticle(p.Context, p.Args["id"].(int), input.changes())
+					if errors.Is(err, repositories.ErrNotFound) {
+						return nil, errArticleNotFound
+					}
+					if err != nil {
+						return nil, storeError{cause: err}
+					}
+					return article, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// graphQLInput reads an ArticleInput argument, which the executor has
+// already coerced to a map. Fields that are left out or null stay nil.
+func graphQLInput(arg interface{}) articleInput {
+	fields, _ := arg.(map[string]interface{})
+	input := articleInput{}
+	input.Title, _ = fields["title"].(string)
+	input.Content, _ = fields["content"].(string)
+	if author, ok := fields["author"].(string); ok {
+		input.Author = &author
+	}
+	if tags, ok := fields["tags"].([]interface{}); ok {
+		list := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			if tag, ok := tag.(string); ok {
+				list = append(list, tag)
+			}
+		}
+		input.Tags = &list
+	}
+	return input
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/brothergiez/restful-api/buildinfo"
+	"github.com/gin-gonic/gin"
+)
+
+// ReadinessCheck is a named dependency that must be available before the API
+// can serve traffic.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type HealthHandler struct {
+	Checks  []ReadinessCheck
+	Timeout time.Duration
+}
+
+func NewHealthHandler(checks ...ReadinessCheck) *HealthHandler {
+	return &HealthHandler{
+		Checks:  checks,
+		Timeout: 2 * time.Second,
+	}
+}
+
+// LivenessHandler reports that the process is up and serving requests.
+func (h *HealthHandler) LivenessHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// ReadinessHandler runs every readiness check and answers 503 when any of
+// them fails.
+func (h *HealthHandler) ReadinessHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.Timeout)
+	defer cancel()
+
+	status := http.StatusOK
+	checks := gin.H{}
+	for _, check := range h.Checks {
+		if err := check.Check(ctx); err != nil {
+			status = http.StatusServiceUnavailable
+			checks[check.Name] = err.Error()
+			continue
+		}
+		checks[check.Name] = "ok"
+	}
+
+	body := gin.H{"status": "ok", "checks": checks}
+	if status != http.StatusOK {
+		body["status"] = "unavailable"
+	}
+	c.JSON(status, body)
+}
+
+func (h *HealthHandler) VersionHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, buildinfo.Get())
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLivenessHandler(t *testing.T) {
+	handler := NewHealthHandler()
+
+	router := gin.Default()
+	router.GET("/healthz", handler.LivenessHandler)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, resp.Body.String())
+}
+
+func TestReadinessHandler(t *testing.T) {
+	failing := errors.New("connection refused")
+	handler := NewHealthHandler(
+		ReadinessCheck{Name: "repository", Check: func(ctx context.Context) error { return nil }},
+		ReadinessCheck{Name: "migrations", Check: func(ctx context.Context) error { return failing }},
+	)
+
+	router := gin.Default()
+	router.GET("/readyz", handler.ReadinessHandler)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.JSONEq(t, `{"status":"unavailable","checks":{"repository":"ok","migrations":"connection refused"}}`, resp.Body.String())
+
+	handler.Checks = handler.Checks[:1]
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"status":"ok","checks":{"repository":"ok"}}`, resp.Body.String())
+}
+
+func TestVersionHandler(t *testing.T) {
+	handler := NewHealthHandler()
+
+	router := gin.Default()
+	router.GET("/version", handler.VersionHandler)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var info map[string]interface{}
+	err := json.Unmarshal(resp.Body.Bytes(), &info)
+	assert.NoError(t, err)
+	assert.Equal(t, runtime.Version(), info["goVersion"])
+	assert.Contains(t, info, "commit")
+	assert.Contains(t, info, "buildTime")
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+
+	"github.com/brothergiez/restful-api/models"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/brothergiez/restful-api/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// exportBatchSize is how many articles are read from the repository and
+	// flushed to the client at a time.
+	exportBatchSize = 500
+	// importBatchSize is how many valid lines are stored per repository call.
+	importBatchSize = 500
+	// maxImportLineSize is the longest NDJSON line accepted.
+	maxImportLineSize = 1 << 20
+	// maxImportErrors caps the rejected lines listed in the import summary.
+	maxImportErrors = 100
+)
+
+type importError struct {
+	Line  int    `json:"line" xml:"line"`
+	Error string `json:"error" xml:"error"`
+}
+
+type importSummary struct {
+	XMLName  xml.Name      `json:"-" xml:"importSummary"`
+	Mode     string        `json:"mode" xml:"mode"`
+	Accepted int           `json:"accepted" xml:"accepted"`
+	Created  int           `json:"created" xml:"created"`
+	Updated  int           `json:"updated" xml:"updated"`
+	Rejected int           `json:"rejected" xml:"rejected"`
+	Errors   []importError `json:"errors,omitempty" xml:"errors>error,omitempty"`
+	// Error is set when the import stopped early because the stream could
+	// not be read or a batch could not be stored. Batches stored before it
+	// are kept.
+	Error string `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// ExportArticlesHandler streams every article as one JSON object per line.
+// Articles are read and flushed in batches, so the response is never held in
+// memory as a whole.
+func (h *ArticleHandler) ExportArticlesHandler(c *gin.Context) {
+	ctx, span := startSpan(c, "ExportArticlesHandler")
+	defer span.End()
+
+	if _, ok := negotiate(c, []string{mimeNDJSON}); !ok {
+		return
+	}
+
+	c.Header("Content-Type", mimeNDJSON)
+	c.Header("Content-Disposition", `attachment; filename="articles.ndjson"`)
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+
+	encoder := json.NewEncoder(c.Writer)
+	afterID := 0
+	for ctx.Err() == nil {
+		articles := h.Repo.ArticlesAfter(ctx, afterID, exportBatchSize)
+		if len(articles) == 0 {
+			return
+		}
+		for _, article := range articles {
+			if err := encoder.Encode(article); err != nil {
+				// The client went away; there is no one left to report to.
+				_ = c.Error(err)
+				return
+			}
+		}
+		c.Writer.Flush()
+		afterID = articles[len(articles)-1].ID
+	}
+}
+
+// parseImportLine decodes and validates one NDJSON line.
+func parseImportLine(line []byte, mode repositories.ImportMode) (models.Article, error) {
+	var article models.Article
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&article); err != nil {
+		return article, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if decoder.More() {
+		return article, fmt.Errorf("invalid JSON: more than one value on the line")
+	}
+	if article.Title == "" || article.Content == "" {
+		return article, fmt.Errorf("title and content are required")
+	}
+	if mode == repositories.ImportUpsert && article.ID < 1 {
+		return article, fmt.Errorf("a positive id is required in upsert mode")
+	}
+	return article, nil
+}
+
+// ImportArticlesHandler reads an NDJSON stream of articles. Valid lines are
+// stored in batches as they arrive; invalid ones are counted and reported in
+// the summary without stopping the import. The mode query parameter selects
+// append (the default) or upsert. If a batch cannot be stored the import
+// stops with 500; earlier batches are kept.
+func (h *ArticleHandler) ImportArticlesHandler(c *gin.Context) {
+	ctx, span := startSpan(c, "ImportArticlesHandler")
+	defer span.End()
+
+	format, ok := negotiate(c, itemFormats)
+	if !ok {
+		return
+	}
+
+	mode := repositories.ImportMode(c.DefaultQuery("mode", string(repositories.ImportAppend)))
+	if mode != repositories.ImportAppend && mode != repositories.ImportUpsert {
+		c.JSON(http.StatusBadRequest, requestid.ErrorBody(c, "Invalid mode: use append or upsert"))
+		return
+	}
+
+	summary := importSummary{Mode: string(mode)}
+	batch := make([]models.Article, 0, importBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		result, err := h.Repo.ImportArticles(ctx, batch, mode)
+		if err != nil {
+			return err
+		}
+		summary.Created += result.Created
+		summary.Updated += result.Updated
+		batch = batch[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(c.Request.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineSize)
+	lineNumber := 0
+	storeFailed := func(err error) {
+		_ = c.Error(err)
+		summary.Accepted -= len(batch)
+		summary.Error = fmt.Sprintf("failed to store the articles read up to line %d", lineNumber)
+		respond(c, format, http.StatusInternalServerError, summary)
+	}
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		article, err := parseImportLine(line, mode)
+		if err != nil {
+			summary.Rejected++
+			if len(summary.Errors) < maxImportErrors {
+				summary.Errors = append(summary.Errors, importError{Line: lineNumber, Error: err.Error()})
+			}
+			continue
+		}
+
+		summary.Accepted++
+		batch = append(batch, article)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				storeFailed(err)
+				return
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		storeFailed(err)
+		return
+	}
+
+	if err := scanner.Err(); err != nil {
+		summary.Error = fmt.Sprintf("stopped reading after line %d: %v", lineNumber, err)
+		respond(c, format, http.StatusBadRequest, summary)
+		return
+	}
+	respond(c, format, http.StatusOK, summary)
+}
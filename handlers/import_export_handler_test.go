@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/brothergiez/restful-api/models"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupImportExportRouter(repo *repositories.ArticleRepository) *gin.Engine {
+	handler := NewArticleHandler(repo)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/articles/export", handler.ExportArticlesHandler)
+	router.POST("/articles/import", handler.ImportArticlesHandler)
+	return router
+}
+
+func postImport(router *gin.Engine, query, body string) (*httptest.ResponseRecorder, importSummary) {
+	req := httptest.NewRequest(http.MethodPost, "/articles/import"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", mimeNDJSON)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var summary importSummary
+	_ = json.Unmarshal(resp.Body.Bytes(), &summary)
+	return resp, summary
+}
+
+func TestExportArticlesHandler(t *testing.T) {
+	repo := repositories.NewArticleRepository()
+	total := exportBatchSize*2 + 1
+	for i := 1; i <= total; i++ {
+		repo.CreateArticle(context.Background(), models.Article{Title: "Title " + strconv.Itoa(i), Content: "Content"})
+	}
+	router := setupImportExportRouter(repo)
+
+	req := httptest.NewRequest(http.MethodGet, "/articles/export", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, mimeNDJSON, resp.Header().Get("Content-Type"))
+	assert.True(t, resp.Flushed)
+
+	scanner := bufio.NewScanner(resp.Body)
+	count := 0
+	for scanner.Scan() {
+		var article models.Article
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &article))
+		count++
+		assert.Equal(t, count, article.ID)
+	}
+	assert.Equal(t, total, count)
+
+	req = httptest.NewRequest(http.MethodGet, "/articles/export", nil)
+	req.Header.Set("Accept", "text/csv")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNotAcceptable, resp.Code)
+}
+
+func TestImportArticlesHandler(t *testing.T) {
+	repo := repositories.NewArticleRepository()
+	repo.CreateArticle(context.Background(), models.Article{Title: "First", Content: "One"})
+	router := setupImportExportRouter(repo)
+
+	body := strings.Join([]string{
+		`{"id":1,"title":"First v2","content":"One again"}`,
+		``,
+		`{"id":7,"title":"Seventh","content":"Seven"}`,
+		`{"title":"No ID","content":"Missing"}`,
+		`{"id":8,"title":"","content":"Empty title"}`,
+		`{"id":9,"title":"Extra","content":"Field","views":3}`,
+		`not json`,
+	}, "\n")
+	resp, summary := postImport(router, "?mode=upsert", body)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "upsert", summary.Mode)
+	assert.Equal(t, 2, summary.Accepted)
+	assert.Equal(t, 1, summary.Created)
+	assert.Equal(t, 1, summary.Updated)
+	assert.Equal(t, 4, summary.Rejected)
+	assert.Equal(t, []int{4, 5, 6, 7}, []int{summary.Errors[0].Line, summary.Errors[1].Line, summary.Errors[2].Line, summary.Errors[3].Line})
+	assert.Contains(t, summary.Errors[0].Error, "id is required")
+	assert.Contains(t, summary.Errors[1].Error, "title and content")
+	assert.Contains(t, summary.Errors[2].Error, "unknown field")
+
+	articles, _ := repo.GetAllArticlesWithPagination(context.Background(), 1, 10)
+	assert.Equal(t, []models.Article{
+		{ID: 1, Title: "First v2", Content: "One again"},
+		{ID: 7, Title: "Seventh", Content: "Seven"},
+	}, articles)
+
+	resp, summary = postImport(router, "", `{"id":1,"title":"Copy","content":"Appended"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "append", summary.Mode)
+	assert.Equal(t, 1, summary.Created)
+	assert.Equal(t, 3, repo.CountArticles(context.Background()))
+}
+
+func TestImportArticlesHandlerErrors(t *testing.T) {
+	repo := repositories.NewArticleRepository()
+	router := setupImportExportRouter(repo)
+
+	resp, _ := postImport(router, "?mode=replace", `{"title":"T","content":"C"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	body := `{"title":"Kept","content":"C"}` + "\n" + `{"title":"` + strings.Repeat("x", maxImportLineSize) + `"}`
+	resp, summary := postImport(router, "", body)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, 1, summary.Created)
+	assert.Contains(t, summary.Error, "token too long")
+	assert.Equal(t, 1, repo.CountArticles(context.Background()))
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	source := repositories.NewArticleRepository()
+	source.CreateArticle(context.Background(), models.Article{Title: "One", Content: "1"})
+	source.CreateArticle(context.Background(), models.Article{Title: "Two", Content: "2"})
+	source.BulkWrite(context.Background(), []repositories.BulkOperation{{Action: repositories.BulkDelete, ID: 1}}, false)
+
+	resp := httptest.NewRecorder()
+	setupImportExportRouter(source).ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/articles/export", nil))
+
+	target := repositories.NewArticleRepository()
+	_, summary := postImport(setupImportExportRouter(target), "?mode=upsert", resp.Body.String())
+	assert.Equal(t, 1, summary.Created)
+
+	articles, _ := target.GetAllArticlesWithPagination(context.Background(), 1, 10)
+	assert.Equal(t, []models.Article{{ID: 2, Title: "Two", Content: "2"}}, articles)
+	next, err := target.CreateArticle(context.Background(), models.Article{Title: "Three", Content: "3"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, next.ID)
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/brothergiez/restful-api/buildinfo"
+	"github.com/brothergiez/restful-api/middlewares"
+	"github.com/brothergiez/restful-api/models"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+)
+
+const (
+	apiKeyScheme = "apiKey"
+	articlesTag  = "articles"
+)
+
+// errorResponse is the body written by requestid.ErrorBody.
+type errorResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// OpenAPISpec describes the article routes as an OpenAPI 3 document. The
+// schemas are generated from the types the handlers read and write, so they
+// follow the code.
+func OpenAPISpec() (*openapi3.T, error) {
+	version := buildinfo.Get().Version
+	if version == "" {
+		version = "dev"
+	}
+	doc := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:       "Articles API",
+			Description: "Create, search, page through, import, export and watch articles.",
+			Version:     version,
+		},
+		Paths: openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas: openapi3.Schemas{},
+			SecuritySchemes: openapi3.SecuritySchemes{
+				apiKeyScheme: &openapi3.SecuritySchemeRef{Value: openapi3.NewSecurityScheme().
+					WithType("apiKey").WithIn("header").WithName("X-API-Key").
+					WithDescription("Optional. A configured key identifies the client for rate limiting.")},
+			},
+		},
+		Security: openapi3.SecurityRequirements{openapi3.NewSecurityRequirement().Authenticate(apiKeyScheme), openapi3.NewSecurityRequirement()},
+		Tags:     openapi3.Tags{{Name: articlesTag}},
+	}
+
+	// Responses always carry the fields that are not omitted when empty;
+	// requests may leave any field out.
+	if err := generateSchemas(doc.Components.Schemas, requireNonEmptyFields,
+		models.Article{}, articlePage{}, bulkResponse{}, importSummary{}, errorResponse{}); err != nil {
+		return nil, err
+	}
+	if err := generateSchemas(doc.Components.Schemas, nil, articleInput{}, bulkRequest{}); err != nil {
+		return nil, err
+	}
+	doc.Components.Schemas["BulkOperation"].Value.Required = []string{"action"}
+	// Generated schemas are shared between fields, so they are replaced
+	// rather than changed.
+	doc.Components.Schemas["BulkOperation"].Value.Properties["action"] = openapi3.NewStringSchema().
+		WithEnum(repositories.BulkCreate, repositories.BulkUpdate, repositories.BulkDelete).NewRef()
+	doc.Components.Schemas["BulkRequest"].Value.Required = []string{"operations"}
+	// An update replaces the title and content, so unlike a create it needs
+	// both.
+	articleUpdate := *doc.Components.Schemas["ArticleInput"].Value
+	articleUpdate.Required = []string{"title", "content"}
+	doc.Components.Schemas["ArticleUpdate"] = openapi3.NewSchemaRef("", &articleUpdate)
+
+	for _, route := range articleOperations() {
+		doc.AddOperation(route.path, route.method, route.operation)
+	}
+	// Fill in the values behind the component references.
+	if err := openapi3.NewLoader().ResolveRefsIn(doc, nil); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// generateSchemas adds the schemas of values, and of the structs they
+// contain, to schemas under their exported type names.
+func generateSchemas(schemas openapi3.Schemas, customize openapi3gen.SchemaCustomizerFn, values ...any) error {
+	opts := []openapi3gen.Option{
+		openapi3gen.CreateComponentSchemas(openapi3gen.ExportComponentSchemasOptions{
+			ExportComponentSchemas: true,
+			ExportTopLevelSchema:   true,
+		}),
+		openapi3gen.CreateTypeNameGenerator(schemaName),
+	}
+	if customize != nil {
+		opts = append(opts, openapi3gen.SchemaCustomizer(customize))
+	}
+	generator := openapi3gen.NewGenerator(opts...)
+	for _, value := range values {
+		if _, err := generator.NewSchemaRefForValue(value, schemas); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func schemaName(t reflect.Type) string {
+	name := []rune(t.Name())
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
+
+// requireNonEmptyFields marks the JSON fields of a struct that are always
+// written as required.
+func requireNonEmptyFields(_ string, t reflect.Type, _ reflect.StructTag, schema *openapi3.Schema) error {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		name, options, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || strings.Contains(options, "omitempty") {
+			continue
+		}
+		schema.Required = append(schema.Required, name)
+	}
+	return nil
+}
+
+type articleOperation struct {
+	method    string
+	path      string
+	operation *openapi3.Operation
+}
+
+func articleOperations() []articleOperation {
+	article := schemaRef("Article")
+	articles := openapi3.NewSchemaRef("", &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeArray}, Items: article})
+	idParameter := openapi3.NewPathParameter("id").WithSchema(openapi3.NewIntegerSchema())
+
+	create := newOperation("createArticle", "Create an article")
+	create.RequestBody = jsonBody(schemaRef("ArticleInput"))
+	create.AddParameter(idempotencyKeyParameter())
+	setResponse(create, http.StatusCreated, "The created article", negotiatedContent(article, itemFormats))
+	setErrors(create, http.StatusBadRequest, http.StatusNotAcceptable, http.StatusConflict, http.StatusInternalServerError)
+
+	update := newOperation("updateArticle", "Update the title, content, author and tags of an article")
+	update.Description = "The title and content are replaced. The author and tags are kept when they are " +
+		"left out or null; an empty string or list clears them."
+	update.RequestBody = jsonBody(schemaRef("ArticleUpdate"))
+	update.AddParameter(idParameter)
+	setResponse(update, http.StatusOK, "The updated article", negotiatedContent(article, itemFormats))
+	setErrors(update, http.StatusBadRequest, http.StatusNotFound, http.StatusNotAcceptable, http.StatusInternalServerError)
+
+	search := newOperation("searchArticles", "Find the articles whose title or content contains a keyword")
+	search.AddParameter(openapi3.NewQueryParameter("keyword").WithSchema(openapi3.NewStringSchema()))
+	setResponse(search, http.StatusOK, "The matching articles", negotiatedContent(articles, listFormats))
+	setErrors(search, http.StatusNotAcceptable)
+
+	list := newOperation("listArticles", "Page through the articles")
+	list.AddParameter(openapi3.NewQueryParameter("page").
+		WithSchema(openapi3.NewIntegerSchema().WithMin(1).WithDefault(1)))
+	list.AddParameter(openapi3.NewQueryParameter("limit").
+		WithSchema(openapi3.NewIntegerSchema().WithMin(1).WithDefault(10)))
+	setResponse(list, http.StatusOK, "One page of articles", negotiatedContent(schemaRef("ArticlePage"), listFormats))
+	setErrors(list, http.StatusBadRequest, http.StatusNotAcceptable)
+
+	bulk := newOperation("bulkArticles", "Create, update and delete articles in one request")
+	bulk.Description = "Each result carries the status the single request would have returned. " +
+		"An update keeps the author and tags it leaves out, like the single update. " +
+		"An atomic batch is rolled back as a whole when any operation fails."
+	bulk.RequestBody = jsonBody(schemaRef("BulkRequest"))
+	bulk.AddParameter(idempotencyKeyParameter())
+	bulkResult := negotiatedContent(schemaRef("BulkResponse"), itemFormats)
+	setResponse(bulk, http.StatusOK, "Every operation succeeded", bulkResult)
+	setResponse(bulk, http.StatusMultiStatus, "Some operations failed", bulkResult)
+	setResponse(bulk, http.StatusUnprocessableEntity, "The atomic batch was rolled back", bulkResult)
+	setErrors(bulk, http.StatusBadRequest, http.StatusNotAcceptable, http.StatusConflict,
+		http.StatusRequestEntityTooLarge, http.StatusInternalServerError)
+
+	export := newOperation("exportArticles", "Download every article as NDJSON, one article per line")
+	setResponse(export, http.StatusOK, "The articles", openapi3.NewContentWithSchemaRef(article, []string{mimeNDJSON}))
+	setErrors(export, http.StatusNotAcceptable)
+
+	importOp := newOperation("importArticles", "Store articles sent as NDJSON, one article per line")
+	importOp.Description = "Invalid lines are counted and listed in the summary without stopping the import. " +
+		"Upsert keeps the IDs given and replaces the articles that exist."
+	importOp.RequestBody = &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().WithRequired(true).
+		WithSchemaRef(article, []string{mimeNDJSON})}
+	importOp.AddParameter(openapi3.NewQueryParameter("mode").
+		WithSchema(openapi3.NewStringSchema().WithEnum("append", "upsert").WithDefault("append")))
+	importOp.AddParameter(idempotencyKeyParameter())
+	importSummary := schemaRef("ImportSummary")
+	setResponse(importOp, http.StatusOK, "The import summary", negotiatedContent(importSummary, itemFormats))
+	setResponse(importOp, http.StatusBadRequest,
+		"Invalid mode, or the stream could not be read, in which case the summary lists what was stored",
+		negotiatedContent(openapi3.NewSchemaRef("", &openapi3.Schema{
+			OneOf: openapi3.SchemaRefs{importSummary, schemaRef("ErrorResponse")},
+		}), itemFormats))
+	setResponse(importOp, http.StatusInternalServerError, "A batch could not be stored; earlier batches are kept",
+		negotiatedContent(importSummary, itemFormats))
+	setErrors(importOp, http.StatusNotAcceptable, http.StatusConflict)
+
+	stream := newOperation("streamArticles", "Watch article changes as server-sent events")
+	stream.Description = "Events are named after their type and carry the article as JSON. " +
+		"A reset event means changes were missed and the articles should be reloaded."
+	stream.AddParameter(openapi3.NewQueryParameter("tag").WithSchema(openapi3.NewStringSchema()))
+	stream.AddParameter(openapi3.NewQueryParameter("author").WithSchema(openapi3.NewStringSchema()))
+	stream.AddParameter(openapi3.NewHeaderParameter("Last-Event-ID").
+		WithDescription("The last event received, to resume after a reconnect.").
+		WithSchema(openapi3.NewStringSchema()))
+	setResponse(stream, http.StatusOK, "The event stream",
+		openapi3.NewContentWithSchema(openapi3.NewStringSchema(), []string{"text/event-stream"}))
+	setErrors(stream, http.StatusBadRequest, http.StatusServiceUnavailable)
+
+	presence := newOperation("articlePresence", "Open a WebSocket showing who is viewing or editing an article")
+	presence.AddParameter(idParameter)
+	presence.AddParameter(openapi3.NewQueryParameter("user").WithRequired(true).
+		WithSchema(openapi3.NewStringSchema().WithMinLength(1).WithMaxLength(maxUserLength)))
+	setResponse(presence, http.StatusSwitchingProtocols, "Switched to the WebSocket protocol", nil)
+	setErrors(presence, http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable)
+
+	return []articleOperation{
+		{http.MethodPost, "/articles/create", create},
+		{http.MethodPut, "/articles/update/{id}", update},
+		{http.MethodGet, "/articles/search", search},
+		{http.MethodGet, "/articles/get-all", list},
+		{http.MethodPost, "/articles/bulk", bulk},
+		{http.MethodGet, "/articles/export", export},
+		{http.MethodPost, "/articles/import", importOp},
+		{http.MethodGet, "/articles/stream", stream},
+		{http.MethodGet, "/articles/presence/{id}", presence},
+	}
+}
+
+func schemaRef(name string) *openapi3.SchemaRef {
+	return openapi3.NewSchemaRef("#/components/schemas/"+name, nil)
+}
+
+// newOperation starts an operation with the response every article route
+// can give: 429 when rate limited.
+func newOperation(id, summary string) *openapi3.Operation {
+	operation := openapi3.NewOperation()
+	operation.OperationID = id
+	operation.Summary = summary
+	operation.Tags = []string{articlesTag}
+	operation.Responses = openapi3.NewResponsesWithCapacity(8)
+
+	limited := errorResponseFor(http.StatusTooManyRequests)
+	limited.Headers = openapi3.Headers{"Retry-After": &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+		Description: "Seconds until a request is allowed again.",
+		Schema:      openapi3.NewIntegerSchema().NewRef(),
+	}}}}
+	operation.AddResponse(http.StatusTooManyRequests, limited)
+	return operation
+}
+
+func jsonBody(schema *openapi3.SchemaRef) *openapi3.RequestBodyRef {
+	return &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().WithRequired(true).WithJSONSchemaRef(schema)}
+}
+
+func idempotencyKeyParameter() *openapi3.Parameter {
+	return openapi3.NewHeaderParameter(middlewares.IdempotencyKeyHeader).
+		WithDescription("Replays the stored response when the request is repeated with the same key. " +
+			"Reusing a key for a different request answers 422, and 503 is returned while too many " +
+			"requests with a key are still being processed.").
+		WithSchema(openapi3.NewStringSchema().WithMaxLength(middlewares.MaxIdempotencyKeyLength))
+}
+
+// negotiatedContent offers schema in each of formats. CSV is a table of
+// articles rather than the JSON structure.
+func negotiatedContent(schema *openapi3.SchemaRef, formats []string) openapi3.Content {
+	content := openapi3.Content{}
+	for _, format := range formats {
+		if format == mimeCSV {
+			content[format] = openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema())
+			continue
+		}
+		content[format] = openapi3.NewMediaType().WithSchemaRef(schema)
+	}
+	return content
+}
+
+func setResponse(operation *openapi3.Operation, status int, description string, content openapi3.Content) {
+	operation.AddResponse(status, openapi3.NewResponse().WithDescription(description).WithContent(content))
+}
+
+func setErrors(operation *openapi3.Operation, statuses ...int) {
+	for _, status := range statuses {
+		operation.AddResponse(status, errorResponseFor(status))
+	}
+}
+
+func errorResponseFor(status int) *openapi3.Response {
+	return openapi3.NewResponse().WithDescription(http.StatusText(status)).
+		WithJSONSchemaRef(schemaRef("ErrorResponse"))
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	doc, err := OpenAPISpec()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, doc.Validate(context.Background()))
+
+	schemas := doc.Components.Schemas
+	article := schemas["Article"].Value
+	assert.ElementsMatch(t, []string{"id", "title", "content", "author", "tags"}, keys(article.Properties))
+	assert.ElementsMatch(t, []string{"id", "title", "content"}, article.Required)
+	assert.ElementsMatch(t, []string{"title", "content", "author", "tags"}, keys(schemas["ArticleInput"].Value.Properties))
+	assert.Empty(t, schemas["ArticleInput"].Value.Required)
+	assert.ElementsMatch(t, keys(schemas["ArticleInput"].Value.Properties), keys(schemas["ArticleUpdate"].Value.Properties))
+	assert.ElementsMatch(t, []string{"title", "content"}, schemas["ArticleUpdate"].Value.Required)
+	assert.Equal(t, "#/components/schemas/ArticleUpdate",
+		doc.Paths.Value("/articles/update/{id}").Put.RequestBody.Value.Content.Get(mimeJSON).Schema.Ref)
+	assert.NotContains(t, schemas["ArticlePage"].Value.Properties, "XMLName")
+	assert.Equal(t, "#/components/schemas/Article", schemas["ArticlePage"].Value.Properties["articles"].Value.Items.Ref)
+	assert.Equal(t, "#/components/schemas/BulkOperation", schemas["BulkRequest"].Value.Properties["operations"].Value.Items.Ref)
+	assert.Equal(t, []any{repositories.BulkCreate, repositories.BulkUpdate, repositories.BulkDelete}, schemas["BulkOperation"].Value.Properties["action"].Value.Enum)
+	assert.Nil(t, schemas["BulkOperation"].Value.Properties["title"].Value.Enum)
+	for _, name := range []string{"BulkResponse", "BulkItemResult", "ImportSummary", "ImportError", "ErrorResponse"} {
+		assert.Contains(t, schemas, name)
+	}
+
+	assert.Contains(t, doc.Security, openapi3.SecurityRequirement{}, "the API key is optional")
+
+	create := doc.Paths.Value("/articles/create").Post
+	assert.Contains(t, create.Responses.Value("201").Value.Content, mimeMsgPack)
+	assert.NotNil(t, create.Parameters.GetByInAndName("header", "Idempotency-Key"))
+	for _, path := range doc.Paths.Map() {
+		for method, operation := range path.Operations() {
+			assert.Nil(t, operation.Responses.Value("401"), method)
+			assert.NotNil(t, operation.Responses.Value("429"), method)
+		}
+	}
+}
+
+func keys[V any](m map[string]V) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	return result
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brothergiez/restful-api/middlewares"
+	"github.com/brothergiez/restful-api/presence"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/brothergiez/restful-api/requestid"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// presenceWriteWait bounds each write to a presence connection.
+	presenceWriteWait = 10 * time.Second
+	// presenceMaxMessage is the largest frame a client may send.
+	presenceMaxMessage = 4096
+	maxUserLength      = 64
+)
+
+// WithPresence enables ArticlePresenceHandler. Browsers connecting from
+// another origin must be listed in allowedOrigins, written as for CORS;
+// without any only same-origin pages may connect.
+func WithPresence(hub *presence.Hub, allowedOrigins []string) ArticleHandlerOption {
+	return func(h *ArticleHandler) {
+		h.presence = hub
+		h.upgrader = websocket.Upgrader{}
+		if len(allowedOrigins) > 0 {
+			originAllowed := middlewares.OriginMatcher(allowedOrigins)
+			h.upgrader.CheckOrigin = func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				return origin == "" || originAllowed(origin)
+			}
+		}
+	}
+}
+
+// ArticlePresenceHandler upgrades to a WebSocket that shows who is viewing or
+// editing an article, relays their cursors and reports changes to the
+// article. The user query parameter names the client to the others.
+func (h *ArticleHandler) ArticlePresenceHandler(c *gin.Context) {
+	ctx, span := startSpan(c, "ArticlePresenceHandler")
+	defer span.End()
+
+	if h.presence == nil {
+		c.JSON(http.StatusServiceUnavailable, requestid.ErrorBody(c, "Presence is not enabled"))
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, requestid.ErrorBody(c, "Invalid ID"))
+		return
+	}
+	user := strings.TrimSpace(c.Query("user"))
+	if user == "" || len(user) > maxUserLength {
+		c.JSON(http.StatusBadRequest, requestid.ErrorBody(c, "Invalid input: user is required and at most 64 characters"))
+		return
+	}
+	if _, err := h.Repo.GetArticle(ctx, id); errors.Is(err, repositories.ErrNotFound) {
+		c.JSON(http.StatusNotFound, requestid.ErrorBody(c, "Article not found"))
+		return
+	}
+
+	client, err := h.presence.Join(id, user)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, requestid.ErrorBody(c, err.Error()))
+		return
+	}
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already answered.
+		client.Leave()
+		return
+	}
+	defer conn.Close()
+
+	// The server read and write timeouts still apply to the hijacked
+	// connection; the heartbeat takes over from them.
+	_ = conn.NetConn().SetDeadline(time.Time{})
+	conn.SetReadLimit(presenceMaxMessage)
+	conn.SetPongHandler(func(string) error {
+		client.Touch()
+		return nil
+	})
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		writePresence(conn, client, h.presence.PingInterval())
+	}()
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		if messageType == websocket.TextMessage {
+			client.Receive(data)
+		}
+	}
+	client.Leave()
+	<-written
+}
+
+// writePresence sends the client's messages and pings until the session
+// ends, then closes the connection with the reason.
+func writePresence(conn *websocket.Conn, client *presence.Client, pingInterval time.Duration) {
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case msg, ok := <-client.Messages():
+			if !ok {
+				code := websocket.CloseNormalClosure
+				if client.Reason() == presence.ReasonShutdown {
+					code = websocket.CloseGoingAway
+				}
+				_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, client.Reason()),
+					time.Now().Add(presenceWriteWait))
+				conn.Close()
+				return
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(presenceWriteWait))
+			if err := conn.WriteJSON(msg); err != nil {
+				conn.Close()
+				client.Leave()
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(presenceWriteWait)); err != nil {
+				conn.Close()
+				client.Leave()
+			}
+		}
+	}
+}
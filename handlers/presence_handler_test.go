@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/models"
+	"github.com/brothergiez/restful-api/presence"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupPresenceServer(t *testing.T, config presence.Config) (*httptest.Server, *repositories.ArticleRepository, *presence.Hub) {
+	hub := presence.NewHub(config)
+	t.Cleanup(hub.Close)
+	bus := events.NewBus()
+	bus.Subscribe("presence", hub.HandleEvent)
+	repo := repositories.NewArticleRepository(repositories.WithPublisher(bus))
+	repo.CreateArticle(context.Background(), models.Article{Title: "Draft", Content: "Text"})
+	handler := NewArticleHandler(repo, WithPresence(hub, []string{"https://*.example.com"}))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/articles/presence/:id", handler.ArticlePresenceHandler)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, repo, hub
+}
+
+func dialPresence(t *testing.T, server *httptest.Server, path string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + path
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if conn != nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+func readPresence(t *testing.T, conn *websocket.Conn) presence.Message {
+	var msg presence.Message
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	assert.NoError(t, conn.ReadJSON(&msg))
+	return msg
+}
+
+func TestArticlePresenceHandler(t *testing.T) {
+	server, repo, _ := setupPresenceServer(t, presence.DefaultConfig())
+
+	alice, _, err := dialPresence(t, server, "/articles/presence/1?user=alice", nil)
+	assert.NoError(t, err)
+	welcome := readPresence(t, alice)
+	assert.Equal(t, presence.MessageWelcome, welcome.Type)
+
+	bob, _, err := dialPresence(t, server, "/articles/presence/1?user=bob", http.Header{"Origin": {"https://app.example.com"}})
+	assert.NoError(t, err)
+	assert.Len(t, readPresence(t, bob).Sessions, 2)
+	assert.Equal(t, "bob", readPresence(t, alice).Session.User)
+
+	assert.NoError(t, bob.WriteJSON(presence.ClientMessage{Type: presence.MessageCursor, Cursor: &presence.Cursor{Field: "title", Offset: 3}}))
+	msg := readPresence(t, alice)
+	assert.Equal(t, presence.MessageCursor, msg.Type)
+	assert.Equal(t, &presence.Cursor{Field: "title", Offset: 3}, msg.Session.Cursor)
+
+	repo.UpdateArticle(context.Background(), 1, repositories.ArticleChanges{Title: "Final", Content: "Text"})
+	msg = readPresence(t, alice)
+	assert.Equal(t, events.TypeArticleUpdated, msg.Type)
+	assert.Equal(t, "Final", msg.Article.Title)
+	assert.Equal(t, events.TypeArticleUpdated, readPresence(t, bob).Type)
+
+	bob.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	msg = readPresence(t, alice)
+	assert.Equal(t, presence.MessageLeft, msg.Type)
+	assert.Equal(t, "bob", msg.Session.User)
+}
+
+func TestArticlePresenceHandlerEndsSessions(t *testing.T) {
+	config := presence.DefaultConfig()
+	config.PingInterval = 10 * time.Millisecond
+	config.StaleAfter = 50 * time.Millisecond
+	server, _, hub := setupPresenceServer(t, config)
+
+	// A client that never reads does not answer pings and goes stale.
+	silent, _, err := dialPresence(t, server, "/articles/presence/1?user=silent", nil)
+	assert.NoError(t, err)
+	active, _, err := dialPresence(t, server, "/articles/presence/1?user=active", nil)
+	assert.NoError(t, err)
+	readPresence(t, active)
+	assert.Equal(t, presence.MessageLeft, readPresence(t, active).Type)
+	_ = silent
+
+	hub.Close()
+	_, _, err = active.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+}
+
+func TestArticlePresenceHandlerRejects(t *testing.T) {
+	config := presence.DefaultConfig()
+	config.MaxSessions = 1
+	server, _, _ := setupPresenceServer(t, config)
+
+	tests := []struct {
+		path   string
+		header http.Header
+		status int
+	}{
+		{"/articles/presence/abc?user=alice", nil, http.StatusBadRequest},
+		{"/articles/presence/1", nil, http.StatusBadRequest},
+		{"/articles/presence/99?user=alice", nil, http.StatusNotFound},
+		{"/articles/presence/1?user=alice", http.Header{"Origin": {"https://evil.test"}}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		_, resp, err := dialPresence(t, server, tt.path, tt.header)
+		assert.Error(t, err, tt.path)
+		assert.Equal(t, tt.status, resp.StatusCode, tt.path)
+	}
+
+	_, _, err := dialPresence(t, server, "/articles/presence/1?user=alice", nil)
+	assert.NoError(t, err)
+	_, resp, _ := dialPresence(t, server, "/articles/presence/1?user=bob", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	router := gin.New()
+	router.GET("/articles/presence/:id", NewArticleHandler(repositories.NewArticleRepository()).ArticlePresenceHandler)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/articles/presence/1?user=alice", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/brothergiez/restful-api/models"
+	"github.com/brothergiez/restful-api/requestid"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+)
+
+const (
+	mimeJSON    = "application/json"
+	mimeXML     = "application/xml"
+	mimeTextXML = "text/xml"
+	mimeMsgPack = "application/msgpack"
+	mimeCSV     = "text/csv"
+	mimeNDJSON  = "application/x-ndjson"
+)
+
+// msgPackAliases maps the other names clients use for MessagePack.
+var msgPackAliases = map[string]bool{"application/vnd.msgpack": true, "application/x-msgpack": true}
+
+// itemFormats are the media types offered for single articles, in order of
+// preference. JSON comes first so it is used when any type is acceptable.
+var itemFormats = []string{mimeJSON, mimeXML, mimeTextXML, mimeMsgPack}
+
+// listFormats are the media types offered for lists of articles.
+var listFormats = append(append([]string{}, itemFormats...), mimeCSV)
+
+// articlePage is one page of GetAllArticlesHandler.
+type articlePage struct {
+	XMLName    xml.Name         `json:"-" xml:"articlePage"`
+	Page       int              `json:"page" xml:"page"`
+	Limit      int              `json:"limit" xml:"limit"`
+	Total      int              `json:"total" xml:"total"`
+	TotalPages int              `json:"totalPages" xml:"totalPages"`
+	Articles   []models.Article `json:"articles" xml:"articles>article"`
+}
+
+// articleList is how a bare list of articles is written as XML, which needs
+// a single root element.
+type articleList struct {
+	XMLName  xml.Name         `xml:"articles"`
+	Articles []models.Article `xml:"article"`
+}
+
+// negotiateFormat picks the offered media type with the highest weight in
+// the Accept header, ties going to the earlier offer. The most specific
+// matching range decides the weight of an offer. It returns "" when nothing
+// offered is acceptable.
+func negotiateFormat(accept string, offered []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offered[0]
+	}
+
+	type acceptRange struct {
+		mediaType string
+		weight    float64
+	}
+	var ranges []acceptRange
+	for _, entry := range strings.Split(accept, ",") {
+		parts := strings.Split(entry, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(parts[0]))
+		if mediaType == "" {
+			continue
+		}
+		weight := 1.0
+		for _, param := range parts[1:] {
+			if q, found := strings.CutPrefix(strings.TrimSpace(param), "q="); found {
+				if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+					weight = parsed
+				}
+			}
+		}
+		if msgPackAliases[mediaType] {
+			mediaType = mimeMsgPack
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, weight: weight})
+	}
+
+	best, bestWeight := "", 0.0
+	for _, offer := range offered {
+		offerType, _, _ := strings.Cut(offer, "/")
+		weight, specificity := 0.0, -1
+		for _, r := range ranges {
+			var s int
+			switch {
+			case r.mediaType == offer:
+				s = 2
+			case r.mediaType == offerType+"/*":
+				s = 1
+			case r.mediaType == "*/*":
+				s = 0
+			default:
+				continue
+			}
+			if s > specificity {
+				weight, specificity = r.weight, s
+			}
+		}
+		if weight > bestWeight {
+			best, bestWeight = offer, weight
+		}
+	}
+	return best
+}
+
+// negotiate chooses the response format for the request from offered. When
+// none is acceptable it answers 406 and returns false, so handlers call it
+// before doing any work.
+func negotiate(c *gin.Context, offered []string) (string, bool) {
+	format := negotiateFormat(c.GetHeader("Accept"), offered)
+	if format == "" {
+		c.JSON(http.StatusNotAcceptable, requestid.ErrorBody(c, "Not Acceptable: supported types are "+strings.Join(offered, ", ")))
+		return "", false
+	}
+	return format, true
+}
+
+// respond writes data in a format chosen by negotiate. CSV is only offered
+// for a []models.Article or an articlePage; every other value must encode as
+// JSON, XML and MessagePack.
+func respond(c *gin.Context, format string, status int, data interface{}) {
+	c.Header("Vary", "Accept")
+
+	switch format {
+	case mimeXML, mimeTextXML:
+		if articles, ok := data.([]models.Article); ok {
+			data = articleList{Articles: articles}
+		}
+		body, err := xml.Marshal(data)
+		if err != nil {
+			_ = c.Error(err)
+			c.JSON(http.StatusInternalServerError, requestid.ErrorBody(c, "Failed to encode response"))
+			return
+		}
+		c.Data(status, format+"; charset=utf-8", append([]byte(xml.Header), body...))
+	case mimeMsgPack:
+		c.Render(status, render.MsgPack{Data: data})
+	case mimeCSV:
+		articles, _ := data.([]models.Article)
+		if page, ok := data.(articlePage); ok {
+			articles = page.Articles
+			c.Header("X-Total-Count", strconv.Itoa(page.Total))
+		}
+		c.Render(status, csvArticles(articles))
+	default:
+		c.JSON(status, data)
+	}
+}
+
+// csvArticles renders articles as CSV with a header row. Tags are joined
+// with semicolons.
+type csvArticles []models.Article
+
+func (r csvArticles) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"id", "title", "content", "author", "tags"}); err != nil {
+		return err
+	}
+	for _, article := range r {
+		record := []string{
+			strconv.Itoa(article.ID),
+			csvCell(article.Title),
+			csvCell(article.Content),
+			csvCell(article.Author),
+			csvCell(strings.Join(article.Tags, ";")),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvCell prefixes values that spreadsheets would run as formulas with a
+// quote, so they are shown as text.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (r csvArticles) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", mimeCSV+"; charset=utf-8")
+}
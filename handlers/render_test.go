@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brothergiez/restful-api/models"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+)
+
+func setupFormatRouter() *gin.Engine {
+	repo := repositories.NewArticleRepository()
+	repo.CreateArticle(context.Background(), models.Article{Title: "Go Basics", Content: "Learn Go, quickly"})
+	repo.CreateArticle(context.Background(), models.Article{Title: "Go Advanced", Content: "Channels and \"select\"", Author: "gopher", Tags: []string{"go", "concurrency"}})
+	handler := NewArticleHandler(repo)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/articles/create", handler.CreateArticleHandler)
+	router.GET("/articles/search", handler.SearchArticlesHandler)
+	router.GET("/articles/get-all", handler.GetAllArticlesHandler)
+	return router
+}
+
+func getWithAccept(router *gin.Engine, path, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept", accept)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	assert.Equal(t, mimeJSON, negotiateFormat("", listFormats))
+	assert.Equal(t, mimeJSON, negotiateFormat("*/*", listFormats))
+	assert.Equal(t, mimeXML, negotiateFormat("application/xml", listFormats))
+	assert.Equal(t, mimeJSON, negotiateFormat("application/xml;q=0.5, application/json", listFormats))
+	assert.Equal(t, mimeCSV, negotiateFormat("text/csv, */*;q=0.1", listFormats))
+	assert.Equal(t, mimeTextXML, negotiateFormat("text/*", listFormats))
+	assert.Equal(t, mimeMsgPack, negotiateFormat("application/x-msgpack", listFormats))
+	assert.Equal(t, mimeXML, negotiateFormat("application/json;q=0, application/*", listFormats))
+	assert.Equal(t, "", negotiateFormat("text/csv", itemFormats))
+	assert.Equal(t, "", negotiateFormat("text/html", listFormats))
+}
+
+func TestGetAllArticlesHandlerXML(t *testing.T) {
+	resp := getWithAccept(setupFormatRouter(), "/articles/get-all?limit=1", "application/xml")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/xml; charset=utf-8", resp.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", resp.Header().Get("Vary"))
+
+	var page articlePage
+	assert.NoError(t, xml.Unmarshal(resp.Body.Bytes(), &page))
+	assert.Equal(t, 2, page.Total)
+	assert.Equal(t, 2, page.TotalPages)
+	assert.Equal(t, []models.Article{{ID: 1, Title: "Go Basics", Content: "Learn Go, quickly"}}, page.Articles)
+}
+
+func TestSearchArticlesHandlerFormats(t *testing.T) {
+	router := setupFormatRouter()
+
+	resp := getWithAccept(router, "/articles/search?keyword=Go", "application/xml")
+	var list articleList
+	assert.NoError(t, xml.Unmarshal(resp.Body.Bytes(), &list))
+	assert.Len(t, list.Articles, 2)
+
+	resp = getWithAccept(router, "/articles/search?keyword=Go", "application/msgpack")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var articles []models.Article
+	assert.NoError(t, codec.NewDecoderBytes(resp.Body.Bytes(), &codec.MsgpackHandle{}).Decode(&articles))
+	assert.Equal(t, "Go Advanced", articles[1].Title)
+
+	resp = getWithAccept(router, "/articles/search?keyword=Go", "text/csv")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header().Get("Content-Type"))
+	records, err := csv.NewReader(bytes.NewReader(resp.Body.Bytes())).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"id", "title", "content", "author", "tags"},
+		{"1", "Go Basics", "Learn Go, quickly", "", ""},
+		{"2", "Go Advanced", "Channels and \"select\"", "gopher", "go;concurrency"},
+	}, records)
+}
+
+func TestCSVEscapesFormulas(t *testing.T) {
+	resp := httptest.NewRecorder()
+	assert.NoError(t, csvArticles{
+		{ID: 1, Title: "=HYPERLINK(\"http://evil.test\")", Content: "+1", Author: "@admin", Tags: []string{"-2", "go"}},
+		{ID: 2, Title: "Sums", Content: "1+1=2", Author: "\tgopher"},
+	}.Render(resp))
+
+	records, err := csv.NewReader(bytes.NewReader(resp.Body.Bytes())).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "'=HYPERLINK(\"http://evil.test\")", "'+1", "'@admin", "'-2;go"}, records[1])
+	assert.Equal(t, []string{"2", "Sums", "1+1=2", "'\tgopher", ""}, records[2])
+}
+
+func TestNotAcceptable(t *testing.T) {
+	router := setupFormatRouter()
+
+	resp := getWithAccept(router, "/articles/get-all", "text/html")
+	assert.Equal(t, http.StatusNotAcceptable, resp.Code)
+	assert.Contains(t, resp.Body.String(), "text/csv")
+
+	req := httptest.NewRequest(http.MethodPost, "/articles/create", bytes.NewBufferString(`{"title":"T","content":"C"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/csv")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNotAcceptable, resp.Code)
+
+	resp = getWithAccept(router, "/articles/get-all", "application/json")
+	assert.Contains(t, resp.Body.String(), `"total":2`)
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/brothergiez/restful-api/requestid"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultStreamHeartbeat is how often an idle stream sends a comment to keep
+// proxies from closing the connection.
+const DefaultStreamHeartbeat = 15 * time.Second
+
+// streamBuffer is the number of events a stream client may fall behind by
+// before it is disconnected. It can resume with Last-Event-ID.
+const streamBuffer = 64
+
+// resetEvent tells a client that events were missed and it should reload the
+// articles it shows.
+const resetEvent = "reset"
+
+// WithEventStream enables StreamArticlesHandler. heartbeat is the interval of
+// the keep-alive comments.
+func WithEventStream(stream *events.Stream, heartbeat time.Duration) ArticleHandlerOption {
+	return func(h *ArticleHandler) {
+		h.stream = stream
+		h.heartbeat = heartbeat
+	}
+}
+
+// streamFilter selects the events a client asked for with the tag and author
+// query parameters. An update is also sent when only the previous version
+// matched, so the client learns that the article left the filter.
+type streamFilter repositories.ArticleFilter
+
+func (f streamFilter) wants(event events.Event) bool {
+	filter := repositories.ArticleFilter(f)
+	if filter.Matches(event.Subject()) {
+		return true
+	}
+	update, ok := event.(events.ArticleUpdated)
+	return ok && filter.Matches(update.Previous)
+}
+
+// StreamArticlesHandler sends article changes as server-sent events. Each
+// event is named after its type, carries the article as JSON and has an ID a
+// reconnecting client sends back in Last-Event-ID to get the events it
+// missed. When they are no longer buffered a reset event comes first.
+func (h *ArticleHandler) StreamArticlesHandler(c *gin.Context) {
+	_, span := startSpan(c, "StreamArticlesHandler")
+	defer span.End()
+
+	if h.stream == nil {
+		c.JSON(http.StatusServiceUnavailable, requestid.ErrorBody(c, "Article stream is not enabled"))
+		return
+	}
+
+	var replay []events.Sequenced
+	var listener *events.Listener
+	complete := true
+	if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" {
+		lastID, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, requestid.ErrorBody(c, "Invalid Last-Event-ID"))
+			return
+		}
+		replay, listener, complete = h.stream.Resume(lastID, streamBuffer)
+	} else {
+		listener = h.stream.Listen(streamBuffer)
+	}
+	defer listener.Close()
+	filter := streamFilter{Tag: c.Query("tag"), Author: c.Query("author")}
+
+	// The server write timeout would otherwise cut the stream off.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	header := c.Writer.Header()
+	header.Set("Content-Type", sse.ContentType)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if !complete {
+		if err := sse.Encode(c.Writer, sse.Event{Event: resetEvent, Data: "events were missed, reload the articles"}); err != nil {
+			return
+		}
+	}
+	for _, sequenced := range replay {
+		if err := writeStreamEvent(c.Writer, filter, sequenced); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case sequenced, ok := <-listener.Events():
+			if !ok {
+				return
+			}
+			if err := writeStreamEvent(c.Writer, filter, sequenced); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeStreamEvent(w io.Writer, filter streamFilter, sequenced events.Sequenced) error {
+	if !filter.wants(sequenced.Event) {
+		return nil
+	}
+	return sse.Encode(w, sse.Event{
+		Id:    strconv.FormatUint(sequenced.ID, 10),
+		Event: sequenced.Event.Type(),
+		Data:  sequenced.Event.Subject(),
+	})
+}
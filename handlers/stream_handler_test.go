@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/models"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type sseEvent struct {
+	id, event, data string
+}
+
+// sseClient reads the events of one stream response, skipping comments.
+type sseClient struct {
+	resp   *http.Response
+	events chan sseEvent
+}
+
+func openStream(t *testing.T, url, lastEventID string) *sseClient {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	assert.NoError(t, err)
+
+	client := &sseClient{resp: resp, events: make(chan sseEvent, 10)}
+	go func() {
+		defer close(client.events)
+		scanner := bufio.NewScanner(resp.Body)
+		var event sseEvent
+		for scanner.Scan() {
+			field, value, _ := strings.Cut(scanner.Text(), ":")
+			switch field {
+			case "id":
+				event.id = value
+			case "event":
+				event.event = value
+			case "data":
+				event.data = strings.TrimSpace(value)
+			case "":
+				if value == "" {
+					client.events <- event
+					event = sseEvent{}
+				} else {
+					client.events <- sseEvent{event: "comment", data: strings.TrimSpace(value)}
+				}
+			}
+		}
+	}()
+	return client
+}
+
+func (c *sseClient) next(t *testing.T) sseEvent {
+	select {
+	case event := <-c.events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return sseEvent{}
+	}
+}
+
+func setupStreamRouter(t *testing.T, heartbeat time.Duration) (*httptest.Server, *repositories.ArticleRepository, *events.Stream) {
+	stream := events.NewStream(3)
+	bus := events.NewBus()
+	bus.Subscribe("stream", stream.Handle)
+	repo := repositories.NewArticleRepository(repositories.WithPublisher(bus))
+	handler := NewArticleHandler(repo, WithEventStream(stream, heartbeat))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/articles/stream", handler.StreamArticlesHandler)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	t.Cleanup(stream.Close)
+	return server, repo, stream
+}
+
+func TestStreamArticlesHandler(t *testing.T) {
+	server, repo, _ := setupStreamRouter(t, time.Minute)
+	ctx := context.Background()
+
+	all := openStream(t, server.URL+"/articles/stream", "")
+	assert.Equal(t, http.StatusOK, all.resp.StatusCode)
+	assert.Equal(t, "text/event-stream;charset=utf-8", all.resp.Header.Get("Content-Type"))
+	assert.Equal(t, "no-cache", all.resp.Header.Get("Cache-Control"))
+	tagged := openStream(t, server.URL+"/articles/stream?tag=go&author=Gopher", "")
+
+	repo.CreateArticle(ctx, models.Article{Title: "Untagged", Content: "One"})
+	repo.CreateArticle(ctx, models.Article{Title: "Tagged", Content: "Two", Author: "gopher", Tags: []string{"Go"}})
+	repo.UpdateArticle(ctx, 2, repositories.ArticleChanges{Title: "Untagged now", Content: "Two", Tags: &[]string{}})
+
+	assert.Equal(t, sseEvent{id: "1", event: "article.created", data: `{"id":1,"title":"Untagged","content":"One"}`}, all.next(t))
+	assert.Equal(t, "2", all.next(t).id)
+	assert.Equal(t, "article.updated", all.next(t).event)
+
+	// The update is sent because the article matched before it.
+	event := tagged.next(t)
+	assert.Equal(t, "2", event.id)
+	assert.Equal(t, `{"id":2,"title":"Tagged","content":"Two","author":"gopher","tags":["Go"]}`, event.data)
+	assert.Equal(t, sseEvent{id: "3", event: "article.updated", data: `{"id":2,"title":"Untagged now","content":"Two","author":"gopher"}`}, tagged.next(t))
+}
+
+func TestStreamArticlesHandlerResume(t *testing.T) {
+	server, repo, _ := setupStreamRouter(t, time.Minute)
+	ctx := context.Background()
+	for _, title := range []string{"One", "Two", "Three", "Four"} {
+		repo.CreateArticle(ctx, models.Article{Title: title, Content: title})
+	}
+
+	resumed := openStream(t, server.URL+"/articles/stream", "2")
+	assert.Equal(t, "3", resumed.next(t).id)
+	assert.Equal(t, "4", resumed.next(t).id)
+	repo.CreateArticle(ctx, models.Article{Title: "Five", Content: "Five"})
+	assert.Equal(t, "5", resumed.next(t).id)
+
+	// Events 2 to 5 no longer fit in the replay buffer of three.
+	reset := openStream(t, server.URL+"/articles/stream", "1")
+	assert.Equal(t, "reset", reset.next(t).event)
+	assert.Equal(t, "3", reset.next(t).id)
+
+	resp, err := http.DefaultClient.Do(func() *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/articles/stream", nil)
+		req.Header.Set("Last-Event-ID", "abc")
+		return req
+	}())
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestStreamArticlesHandlerHeartbeatAndClose(t *testing.T) {
+	server, _, stream := setupStreamRouter(t, 10*time.Millisecond)
+
+	client := openStream(t, server.URL+"/articles/stream", "")
+	assert.Equal(t, sseEvent{event: "comment", data: "ping"}, client.next(t))
+
+	stream.Close()
+	for range client.events {
+	}
+}
+
+func TestStreamArticlesHandlerDisabled(t *testing.T) {
+	router := gin.New()
+	router.GET("/articles/stream", NewArticleHandler(repositories.NewArticleRepository()).StreamArticlesHandler)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/articles/stream", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+}
@@ -0,0 +1,132 @@
+//go:build ignore
+
+// Command swaggerui_gen downloads a Swagger UI release from the npm registry
+// into the swaggerui directory, which is embedded into the binary. The
+// package is checked against the integrity hash the registry publishes for
+// it before anything is written.
+//
+//	go run swaggerui_gen.go -version 5.17.14 -out swaggerui
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const registry = "https://registry.npmjs.org/swagger-ui-dist/"
+
+// files are the parts of the package the docs page needs, and its license.
+var files = []string{"swagger-ui.css", "swagger-ui-bundle.js", "LICENSE"}
+
+func main() {
+	version := flag.String("version", "", "swagger-ui-dist version to download")
+	out := flag.String("out", "swaggerui", "directory to write the files to")
+	flag.Parse()
+	if *version == "" {
+		log.Fatal("-version is required")
+	}
+	if err := generate(*version, *out); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func generate(version, out string) error {
+	var release struct {
+		Dist struct {
+			Tarball   string `json:"tarball"`
+			Integrity string `json:"integrity"`
+		} `json:"dist"`
+	}
+	metadata, err := get(registry + version)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(metadata, &release); err != nil {
+		return fmt.Errorf("read release metadata: %w", err)
+	}
+
+	tarball, err := get(release.Dist.Tarball)
+	if err != nil {
+		return err
+	}
+	algorithm, want, _ := strings.Cut(release.Dist.Integrity, "-")
+	if algorithm != "sha512" {
+		return fmt.Errorf("unsupported integrity %q", release.Dist.Integrity)
+	}
+	sum := sha512.Sum512(tarball)
+	if base64.StdEncoding.EncodeToString(sum[:]) != want {
+		return errors.New("package does not match the integrity hash published by the registry")
+	}
+
+	extracted, err := extract(tarball)
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		data, ok := extracted[name]
+		if !ok {
+			return fmt.Errorf("package has no %s", name)
+		}
+		if err := os.WriteFile(filepath.Join(out, name), data, 0o644); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filepath.Join(out, "VERSION"), []byte(version+"\n"), 0o644)
+}
+
+func get(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// extract returns the wanted files of an npm package tarball, whose entries
+// are all under package/.
+func extract(tarball []byte) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(tarball))
+	if err != nil {
+		return nil, err
+	}
+	wanted := map[string]bool{}
+	for _, name := range files {
+		wanted["package/"+name] = true
+	}
+	extracted := map[string][]byte{}
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return extracted, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg || !wanted[header.Name] {
+			continue
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		extracted[strings.TrimPrefix(header.Name, "package/")] = data
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/brothergiez/restful-api/requestid"
+	"github.com/brothergiez/restful-api/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	Webhooks *webhooks.Dispatcher
+}
+
+func NewWebhookHandler(dispatcher *webhooks.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{Webhooks: dispatcher}
+}
+
+// createdSubscription is the only view of a subscription that includes its
+// secret.
+type createdSubscription struct {
+	webhooks.Subscription
+	Secret string `json:"secret"`
+}
+
+func (h *WebhookHandler) CreateWebhookHandler(c *gin.Context) {
+	var input struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+		Secret string   `json:"secret"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, requestid.ErrorBody(c, "Invalid Input"))
+		return
+	}
+
+	subscription, err := h.Webhooks.Subscribe(input.URL, input.Events, input.Secret)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, requestid.ErrorBody(c, err.Error()))
+		return
+	}
+	c.Header("Location", "/webhooks/"+subscription.ID)
+	c.JSON(http.StatusCreated, createdSubscription{Subscription: subscription, Secret: subscription.Secret})
+}
+
+func (h *WebhookHandler) ListWebhooksHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"webhooks": h.Webhooks.Subscriptions()})
+}
+
+func (h *WebhookHandler) GetWebhookHandler(c *gin.Context) {
+	subscription, err := h.Webhooks.Subscription(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, requestid.ErrorBody(c, "Webhook not found"))
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+func (h *WebhookHandler) DeleteWebhookHandler(c *gin.Context) {
+	if err := h.Webhooks.Unsubscribe(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, requestid.ErrorBody(c, "Webhook not found"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// WebhookDeliveriesHandler lists the logged delivery attempts of a
+// subscription, newest first.
+func (h *WebhookHandler) WebhookDeliveriesHandler(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.Webhooks.Subscription(id); err != nil {
+		c.JSON(http.StatusNotFound, requestid.ErrorBody(c, "Webhook not found"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": h.Webhooks.Deliveries(id)})
+}
+
+func (h *WebhookHandler) ListDeadLettersHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"deadLetters": h.Webhooks.DeadLetters()})
+}
+
+// RetryDeadLetterHandler queues a dead letter for delivery again. The result
+// shows up in the delivery log, or as a new dead letter.
+func (h *WebhookHandler) RetryDeadLetterHandler(c *gin.Context) {
+	err := h.Webhooks.RetryDeadLetter(c.Param("id"))
+	switch {
+	case err == nil:
+		c.Status(http.StatusAccepted)
+	case errors.Is(err, webhooks.ErrNotFound):
+		c.JSON(http.StatusNotFound, requestid.ErrorBody(c, "Dead letter or its webhook not found"))
+	default:
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, requestid.ErrorBody(c, "Failed to retry delivery"))
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/brothergiez/restful-api/webhooks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupWebhookRouter(t *testing.T) (*gin.Engine, *webhooks.Dispatcher) {
+	config := webhooks.DefaultConfig()
+	config.InitialBackoff = time.Millisecond
+	config.AllowPrivateTargets = true
+	dispatcher := webhooks.NewDispatcher(config)
+	t.Cleanup(func() { dispatcher.Close(context.Background()) })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewWebhookHandler(dispatcher)
+	router.POST("/webhooks", handler.CreateWebhookHandler)
+	router.GET("/webhooks", handler.ListWebhooksHandler)
+	router.GET("/webhooks/dead-letters", handler.ListDeadLettersHandler)
+	router.POST("/webhooks/dead-letters/:id/retry", handler.RetryDeadLetterHandler)
+	router.GET("/webhooks/:id", handler.GetWebhookHandler)
+	router.DELETE("/webhooks/:id", handler.DeleteWebhookHandler)
+	router.GET("/webhooks/:id/deliveries", handler.WebhookDeliveriesHandler)
+
+	bus := events.NewBus()
+	bus.Subscribe("webhooks", dispatcher.HandleEvent)
+	articles := NewArticleHandler(repositories.NewArticleRepository(repositories.WithPublisher(bus)))
+	router.POST("/articles/create", articles.CreateArticleHandler)
+	router.POST("/articles/bulk", articles.BulkArticlesHandler)
+	return router, dispatcher
+}
+
+func sendJSON(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestWebhookHandlers(t *testing.T) {
+	router, _ := setupWebhookRouter(t)
+
+	resp := sendJSON(router, http.MethodPost, "/webhooks", `{"url":"https://example.com/hook","events":["article.created"]}`)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var created struct {
+		ID     string   `json:"id"`
+		Events []string `json:"events"`
+		Secret string   `json:"secret"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	assert.Len(t, created.Secret, 64)
+	assert.Equal(t, "/webhooks/"+created.ID, resp.Header().Get("Location"))
+
+	resp = sendJSON(router, http.MethodGet, "/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"url":"https://example.com/hook"`)
+	assert.NotContains(t, resp.Body.String(), created.Secret)
+
+	resp = sendJSON(router, http.MethodGet, "/webhooks", "")
+	assert.Contains(t, resp.Body.String(), created.ID)
+	assert.NotContains(t, resp.Body.String(), "secret")
+
+	resp = sendJSON(router, http.MethodGet, "/webhooks/"+created.ID+"/deliveries", "")
+	assert.JSONEq(t, `{"deliveries":[]}`, resp.Body.String())
+
+	resp = sendJSON(router, http.MethodDelete, "/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	resp = sendJSON(router, http.MethodDelete, "/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	resp = sendJSON(router, http.MethodGet, "/webhooks/"+created.ID+"/deliveries", "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	resp = sendJSON(router, http.MethodPost, "/webhooks", `{"url":"https://example.com/hook","events":["article.archived"]}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), `unknown event \"article.archived\"`)
+
+	resp = sendJSON(router, http.MethodPost, "/webhooks/dead-letters/missing/retry", "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestArticleWritesTriggerWebhooks(t *testing.T) {
+	received := make(chan webhooks.Event, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event webhooks.Event
+		_ = json.Unmarshal(body, &event)
+		received <- event
+		if event.Type == webhooks.EventArticleDeleted {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer receiver.Close()
+
+	router, dispatcher := setupWebhookRouter(t)
+	resp := sendJSON(router, http.MethodPost, "/webhooks", `{"url":"`+receiver.URL+`","events":["article.created","article.deleted"]}`)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+
+	sendJSON(router, http.MethodPost, "/articles/create", `{"title":"Hello","content":"World"}`)
+	event := <-received
+	assert.Equal(t, webhooks.EventArticleCreated, event.Type)
+	assert.Equal(t, map[string]any{"id": float64(1), "title": "Hello", "content": "World"}, event.Data)
+
+	// Updates are not subscribed to, and the rolled back batch sends nothing.
+	sendJSON(router, http.MethodPost, "/articles/bulk", `{"atomic":true,"operations":[
+		{"action":"create","title":"Rolled","content":"Back"},
+		{"action":"delete","id":99}
+	]}`)
+	sendJSON(router, http.MethodPost, "/articles/bulk", `{"operations":[
+		{"action":"update","id":1,"title":"Hello v2","content":"World"},
+		{"action":"delete","id":1}
+	]}`)
+	event = <-received
+	assert.Equal(t, webhooks.EventArticleDeleted, event.Type)
+
+	assert.Eventually(t, func() bool { return len(dispatcher.DeadLetters()) == 1 }, time.Second, time.Millisecond)
+	resp = sendJSON(router, http.MethodGet, "/webhooks/dead-letters", "")
+	assert.Contains(t, resp.Body.String(), `"error":"receiver answered 400"`)
+
+	deadLetter := dispatcher.DeadLetters()[0]
+	resp = sendJSON(router, http.MethodPost, "/webhooks/dead-letters/"+deadLetter.ID+"/retry", "")
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	event = <-received
+	assert.Equal(t, deadLetter.EventID, event.ID)
+	assert.Empty(t, received)
+}
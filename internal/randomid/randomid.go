@@ -0,0 +1,16 @@
+// Package randomid generates the random IDs and secrets used across the
+// service.
+package randomid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Hex returns n random bytes encoded as hex, so the result is 2n characters
+// long.
+func Hex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
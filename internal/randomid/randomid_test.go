@@ -0,0 +1,14 @@
+package randomid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHex(t *testing.T) {
+	id := Hex(8)
+	assert.Len(t, id, 16)
+	assert.Regexp(t, "^[0-9a-f]+$", id)
+	assert.NotEqual(t, id, Hex(8))
+}
@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type Config struct {
+	// Format is either "json" (default) or "text".
+	Format string
+	// Level is one of "debug", "info" (default), "warn" or "error".
+	Level string
+	// Output is "stdout" (default), "stderr" or a file path to append to.
+	Output string
+}
+
+// New builds a logger from config. The returned io.Closer releases the output
+// file, if any, and is safe to call for stdout and stderr.
+func New(config Config) (*slog.Logger, io.Closer, error) {
+	level, err := ParseLevel(config.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	output, err := openOutput(config.Output)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	handler, err := NewHandler(output, config.Format, level)
+	if err != nil {
+		output.Close()
+		return nil, nil, err
+	}
+
+	return slog.New(handler), output, nil
+}
+
+// NewHandler returns a JSON or text handler writing to w at the given level,
+// wrapped in a ContextHandler.
+func NewHandler(w io.Writer, format string, level slog.Leveler) (slog.Handler, error) {
+	options := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(format) {
+	case "", "json":
+		return NewContextHandler(slog.NewJSONHandler(w, options)), nil
+	case "text":
+		return NewContextHandler(slog.NewTextHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+}
+
+func ParseLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(value) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q", value)
+	}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+func openOutput(output string) (io.WriteCloser, error) {
+	switch output {
+	case "", "stdout":
+		return nopCloser{os.Stdout}, nil
+	case "stderr":
+		return nopCloser{os.Stderr}, nil
+	default:
+		file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("open log output: %w", err)
+		}
+		return file, nil
+	}
+}
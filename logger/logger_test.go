@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brothergiez/restful-api/requestid"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelInfo, level)
+
+	level, err = ParseLevel("DEBUG")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, level)
+
+	level, err = ParseLevel("warning")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, level)
+
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestNewHandler(t *testing.T) {
+	var buffer bytes.Buffer
+	handler, err := NewHandler(&buffer, "json", slog.LevelInfo)
+	assert.NoError(t, err)
+
+	log := slog.New(handler)
+	log.Debug("hidden")
+	log.Info("request", "status", 200)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(t, lines, 1)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "request", entry["msg"])
+	assert.Equal(t, float64(200), entry["status"])
+
+	buffer.Reset()
+	handler, err = NewHandler(&buffer, "text", slog.LevelInfo)
+	assert.NoError(t, err)
+	slog.New(handler).Info("request", "status", 200)
+	assert.Contains(t, buffer.String(), "msg=request status=200")
+
+	_, err = NewHandler(&buffer, "xml", slog.LevelInfo)
+	assert.Error(t, err)
+}
+
+func TestContextHandler(t *testing.T) {
+	var buffer bytes.Buffer
+	handler, err := NewHandler(&buffer, "json", slog.LevelInfo)
+	assert.NoError(t, err)
+
+	ctx := requestid.NewContext(context.Background(), "abc-123")
+	slog.New(handler).With("component", "test").InfoContext(ctx, "request")
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buffer.Bytes(), &entry))
+	assert.Equal(t, "abc-123", entry["request_id"])
+	assert.Equal(t, "test", entry["component"])
+}
+
+func TestContextHandlerTraceIDs(t *testing.T) {
+	var buffer bytes.Buffer
+	handler, err := NewHandler(&buffer, "json", slog.LevelInfo)
+	assert.NoError(t, err)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	slog.New(handler).InfoContext(ctx, "request")
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buffer.Bytes(), &entry))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entry["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", entry["span_id"])
+}
+
+func TestNewWithFileOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	log, closer, err := New(Config{Format: "json", Level: "info", Output: path})
+	assert.NoError(t, err)
+	log.Info("started")
+	assert.NoError(t, closer.Close())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"msg":"started"`)
+
+	_, _, err = New(Config{Level: "loud"})
+	assert.Error(t, err)
+}
@@ -1,39 +1,187 @@
 package main
 
 import (
-	"log"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/brothergiez/restful-api/config"
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/grpcapi"
 	"github.com/brothergiez/restful-api/handlers"
+	"github.com/brothergiez/restful-api/logger"
+	"github.com/brothergiez/restful-api/metrics"
 	"github.com/brothergiez/restful-api/middlewares"
+	"github.com/brothergiez/restful-api/presence"
 	"github.com/brothergiez/restful-api/repositories"
 	"github.com/brothergiez/restful-api/routes"
+	"github.com/brothergiez/restful-api/server"
+	"github.com/brothergiez/restful-api/tracing"
+	"github.com/brothergiez/restful-api/webhooks"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	err := godotenv.Load()
+	configFile := flag.String("config", "", "path to a YAML or TOML config file (default $CONFIG_FILE)")
+	flag.Parse()
+
+	cfg, err := config.Load(*configFile)
 	if err != nil {
-		log.Println("No .env file found, using default environment variables")
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
 	}
 
-	repo := repositories.NewArticleRepository()
-	handler := handlers.NewArticleHandler(repo)
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(cfg, flag.Args()))
+	}
 
-	router := gin.Default()
+	if err := run(cfg); err != nil {
+		slog.Error("Server stopped with error", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg config.Config) error {
+	log, logCloser, err := logger.New(cfg.ForLogger())
+	if err != nil {
+		return err
+	}
+	defer logCloser.Close()
 
-	router.Use(middlewares.LoggingMiddleware())
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.ForTracing())
+	if err != nil {
+		return err
+	}
 
-	routes.RegisterArticleRoutes(router, handler)
+	appMetrics := metrics.New()
+	dispatcher := webhooks.NewDispatcher(cfg.ForWebhooks(), webhooks.WithLogger(log))
+	bus := events.NewBus(events.WithLogger(log))
+	bus.Subscribe("webhooks", dispatcher.HandleEvent)
+	stream := events.NewStream(cfg.Articles.StreamReplaySize)
+	// The queue holds a whole bulk request, whose events arrive at once.
+	bus.SubscribeAsync("stream", cfg.Articles.BulkMaxOperations, stream.Handle)
+	hub := presence.NewHub(cfg.ForPresence(), presence.WithLogger(log))
+	bus.SubscribeAsync("presence", cfg.Articles.BulkMaxOperations, hub.HandleEvent)
 
-	port := os.Getenv("APP_PORT")
-	if port == "" {
-		port = "8080"
+	repoOptions := []repositories.Option{
+		repositories.WithObserver(appMetrics),
+		repositories.WithPublisher(bus),
+	}
+	var repo *repositories.ArticleRepository
+	if cfg.Storage.DataDir == "" {
+		repo = repositories.NewArticleRepository(repoOptions...)
+	} else {
+		repo, err = repositories.OpenArticleRepository(cfg.ForPersistence(), repoOptions...)
+		if err != nil {
+			return fmt.Errorf("restore articles from %s: %w", cfg.Storage.DataDir, err)
+		}
+		log.Info("Articles restored", "dataDir", cfg.Storage.DataDir, "articles", repo.CountArticles(context.Background()))
+	}
+	appMetrics.RegisterArticleCount(func() int {
+		return repo.CountArticles(context.Background())
+	})
+	handler := handlers.NewArticleHandler(repo,
+		handlers.WithMaxBulkOperations(cfg.Articles.BulkMaxOperations),
+		handlers.WithEventStream(stream, time.Duration(cfg.Articles.StreamHeartbeat)),
+		handlers.WithPresence(hub, cfg.CORS.AllowedOrigins))
+	graphQLHandler, err := handlers.NewGraphQLHandler(repo, cfg.ForGraphQL())
+	if err != nil {
+		return err
+	}
+	docsHandler, err := handlers.NewDocsHandler()
+	if err != nil {
+		return err
 	}
+	healthHandler := handlers.NewHealthHandler(handlers.ReadinessCheck{Name: "repository", Check: repo.Ping})
+
+	router := gin.New()
+	router.Use(middlewares.RequestIDMiddleware())
+	router.Use(middlewares.TracingMiddleware())
+	router.Use(middlewares.MetricsMiddleware(appMetrics))
+	// Compression is registered before logging so the logged response body is
+	// the one written by the handler, not the encoded bytes.
+	router.Use(middlewares.CompressionMiddleware(cfg.ForCompression()))
+	router.Use(middlewares.LoggingMiddleware(log,
+		middlewares.WithRedactor(middlewares.NewRedactor(cfg.ForRedaction())),
+		middlewares.WithBodyLogging(cfg.ForBodyLogging()),
+		middlewares.WithSkipPaths(routes.HealthPaths...),
+	))
+	// Recovery runs inside the middleware above, so a panic is traced,
+	// counted and logged with its request ID as the 500 it turns into.
+	router.Use(gin.Recovery())
+
+	// CORS runs before rate limiting and auth so preflights, which carry no
+	// credentials, are answered without counting against the client.
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		router.Use(middlewares.CORSMiddleware(cfg.ForCORS()))
+	}
+
+	// Keys are checked before rate limiting so that only a valid key gives a
+	// client a bucket of its own. Requests without one are still served.
+	if len(cfg.Auth.APIKeys) > 0 {
+		router.Use(middlewares.APIKeyIdentityMiddleware(cfg.Auth.APIKeys))
+	}
+	rateLimitConfig := cfg.ForRateLimit()
+	router.Use(middlewares.RateLimitMiddleware(rateLimitConfig, middlewares.NewMemoryRateLimitStore(rateLimitConfig.IdleTTL)))
+
+	idempotencyConfig := cfg.ForIdempotency()
+	articleMiddleware := []gin.HandlerFunc{
+		middlewares.IdempotencyMiddleware(idempotencyConfig, middlewares.NewMemoryIdempotencyStore(idempotencyConfig.TTL, idempotencyConfig.MaxEntries)),
+	}
+
+	routes.RegisterArticleRoutes(router, handler, articleMiddleware...)
+	routes.RegisterWebhookRoutes(router, handlers.NewWebhookHandler(dispatcher), articleMiddleware...)
+	routes.RegisterGraphQLRoutes(router, graphQLHandler, articleMiddleware...)
+	routes.RegisterMetricsRoutes(router, appMetrics)
+	routes.RegisterHealthRoutes(router, healthHandler)
+	routes.RegisterDocsRoutes(router, docsHandler)
+
+	srv, err := server.New(router, cfg.ForServer(), log)
+	if err != nil {
+		return err
+	}
+	// Streams and WebSockets end as soon as shutdown starts: streams would
+	// hold up the drain and WebSockets are not waited for at all. Hooks run in
+	// reverse: the gRPC calls finish first, then the repository stops writing,
+	// the event subscribers drain and the webhook deliveries finish.
+	srv.OnDrain(stream.Close)
+	srv.OnDrain(hub.Close)
+	srv.OnShutdown("tracing", shutdownTracing)
+	srv.OnShutdown("webhooks", dispatcher.Close)
+	srv.OnShutdown("events", bus.Close)
+	srv.OnShutdown("repository", repo.Close)
+	if cfg.GRPC.Port != 0 {
+		// gRPC shares the HTTP certificate, so API keys are never sent in
+		// cleartext when TLS is enabled, and the rate limits, with buckets of
+		// its own.
+		grpcServer := grpcapi.NewServer(grpcapi.NewArticleService(repo), cfg.ForGRPC(),
+			grpcapi.WithLogger(log),
+			grpcapi.WithAPIKeys(cfg.Auth.APIKeys),
+			grpcapi.WithTLS(srv.TLSConfig()),
+			grpcapi.WithRateLimit(rateLimitConfig, middlewares.NewMemoryRateLimitStore(rateLimitConfig.IdleTTL)))
+		listener, err := grpcServer.Listen()
+		if err != nil {
+			return fmt.Errorf("listen for gRPC: %w", err)
+		}
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Error("gRPC server stopped with error", "error", err)
+			}
+		}()
+		srv.OnShutdown("grpc", grpcServer.Shutdown)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	log.Printf("Starting server on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	if err := srv.Run(ctx); err != nil {
+		return err
 	}
+	log.Info("Server stopped")
+	return nil
 }
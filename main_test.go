@@ -3,6 +3,8 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -14,14 +16,20 @@ import (
 	"github.com/brothergiez/restful-api/routes"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func setupTestRouter() *gin.Engine {
 	repo := repositories.NewArticleRepository()
 	handler := handlers.NewArticleHandler(repo)
 
-	router := gin.Default()
-	router.Use(middlewares.LoggingMiddleware())
+	router := gin.New()
+	router.Use(middlewares.RequestIDMiddleware())
+	router.Use(middlewares.TracingMiddleware())
+	router.Use(middlewares.LoggingMiddleware(slog.New(slog.NewJSONHandler(io.Discard, nil))))
+	router.Use(gin.Recovery())
 	routes.RegisterArticleRoutes(router, handler)
 
 	return router
@@ -65,3 +73,39 @@ func TestMainInvalidRoute(t *testing.T) {
 
 	assert.Equal(t, http.StatusNotFound, resp.Code)
 }
+
+func TestMainRequestID(t *testing.T) {
+	router := setupTestRouter()
+
+	req := httptest.NewRequest(http.MethodPut, "/articles/update/abc", bytes.NewBufferString(`{}`))
+	req.Header.Set("X-Request-ID", "client-id-1")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "client-id-1", resp.Header().Get("X-Request-ID"))
+	assert.JSONEq(t, `{"error":"Invalid ID","requestId":"client-id-1"}`, resp.Body.String())
+}
+
+func TestMainTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previousProvider)
+
+	router := setupTestRouter()
+
+	payload := `{"title":"Test Title","content":"Test Content"}`
+	req := httptest.NewRequest(http.MethodPost, "/articles/create", bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 3)
+	assert.Equal(t, "ArticleRepository.CreateArticle", spans[0].Name())
+	assert.Equal(t, "ArticleHandler.CreateArticleHandler", spans[1].Name())
+	assert.Equal(t, "POST /articles/create", spans[2].Name())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, spans[2].SpanContext().SpanID(), spans[1].Parent().SpanID())
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics owns a dedicated registry so tests can create as many instances as
+// they need without clashing on the global one.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	requests          *prometheus.CounterVec
+	requestDuration   *prometheus.HistogramVec
+	operationDuration *prometheus.HistogramVec
+	operationFailures *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of HTTP requests by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by method, route template and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		operationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "repository_operation_duration_seconds",
+			Help:    "Article repository operation latency by operation.",
+			Buckets: []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1},
+		}, []string{"operation"}),
+		operationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "repository_operation_errors_total",
+			Help: "Number of failed article repository operations by operation.",
+		}, []string{"operation"}),
+	}
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.operationDuration,
+		m.operationFailures,
+	)
+	return m
+}
+
+// ObserveRequest records a finished HTTP request. route is the gin route
+// template, not the raw path, to keep label cardinality bounded.
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	statusLabel := strconv.Itoa(status)
+	m.requests.WithLabelValues(method, route, statusLabel).Inc()
+	m.requestDuration.WithLabelValues(method, route, statusLabel).Observe(duration.Seconds())
+}
+
+// ObserveOperation implements repositories.Observer.
+func (m *Metrics) ObserveOperation(operation string, duration time.Duration, err error) {
+	m.operationDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if err != nil {
+		m.operationFailures.WithLabelValues(operation).Inc()
+	}
+}
+
+// RegisterArticleCount exposes the number of stored articles, read from count
+// on every scrape.
+func (m *Metrics) RegisterArticleCount(count func() int) {
+	m.Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "articles",
+		Help: "Number of articles currently stored.",
+	}, func() float64 {
+		return float64(count())
+	}))
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserveRequest(t *testing.T) {
+	m := New()
+	m.ObserveRequest("GET", "/articles/search", 200, 5*time.Millisecond)
+	m.ObserveRequest("GET", "/articles/search", 200, 7*time.Millisecond)
+	m.ObserveRequest("GET", "/articles/search", 429, time.Millisecond)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.requests.WithLabelValues("GET", "/articles/search", "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues("GET", "/articles/search", "429")))
+}
+
+func TestObserveOperation(t *testing.T) {
+	m := New()
+	m.ObserveOperation("update", time.Millisecond, nil)
+	m.ObserveOperation("update", time.Millisecond, errors.New("article not found"))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.operationFailures.WithLabelValues("update")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.operationDuration, "repository_operation_duration_seconds"))
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.RegisterArticleCount(func() int { return 3 })
+	m.ObserveRequest("POST", "/articles/create", 201, time.Millisecond)
+
+	resp := httptest.NewRecorder()
+	m.Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "articles 3")
+	assert.Contains(t, resp.Body.String(), `http_requests_total{method="POST",route="/articles/create",status="201"} 1`)
+	assert.Contains(t, resp.Body.String(), "http_request_duration_seconds_bucket")
+}
@@ -0,0 +1,152 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+const truncatedMarker = "...[truncated]"
+
+type BodyLoggingConfig struct {
+	// MaxBodySize is the number of bytes captured from each request and
+	// response body. Anything beyond it is dropped and the logged body ends
+	// with a truncation marker. Zero or less captures nothing.
+	MaxBodySize int
+	// ContentTypes lists the media types whose bodies are captured. Entries
+	// ending in "/" match a whole family, e.g. "text/".
+	ContentTypes []string
+	// SkipRoutes are "METHOD /route/template" entries whose bodies are never
+	// captured.
+	SkipRoutes []string
+	// SampleRate is the probability, between 0 and 1, that a request has its
+	// bodies captured. Requests are always logged, only the bodies are sampled.
+	SampleRate float64
+}
+
+func DefaultBodyLoggingConfig() BodyLoggingConfig {
+	return BodyLoggingConfig{
+		MaxBodySize:  64 * 1024,
+		ContentTypes: []string{"application/json", "application/xml", "application/x-www-form-urlencoded", "text/"},
+		SampleRate:   1,
+	}
+}
+
+type bodyCapture struct {
+	config     BodyLoggingConfig
+	skipRoutes map[string]bool
+	sample     func() float64
+}
+
+func newBodyCapture(config BodyLoggingConfig) *bodyCapture {
+	b := &bodyCapture{
+		config:     config,
+		skipRoutes: map[string]bool{},
+		sample:     rand.Float64,
+	}
+	for _, route := range config.SkipRoutes {
+		method, path, _ := strings.Cut(strings.TrimSpace(route), " ")
+		b.skipRoutes[strings.ToUpper(method)+" "+strings.TrimSpace(path)] = true
+	}
+	return b
+}
+
+// enabled decides whether bodies are captured for a request to route.
+func (b *bodyCapture) enabled(route string) bool {
+	if b.config.MaxBodySize <= 0 || b.skipRoutes[route] {
+		return false
+	}
+	return b.config.SampleRate >= 1 || b.sample() < b.config.SampleRate
+}
+
+func (b *bodyCapture) allowedContentType(contentType string) bool {
+	return contentType == "" || matchContentType(contentType, b.config.ContentTypes)
+}
+
+// matchContentType reports whether the media type of contentType is in
+// allowed. Entries ending in "/" match a whole family, e.g. "text/".
+func matchContentType(contentType string, allowed []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, a := range allowed {
+		if mediaType == a || (strings.HasSuffix(a, "/") && strings.HasPrefix(mediaType, a)) {
+			return true
+		}
+	}
+	return false
+}
+
+// captureRequest reads at most MaxBodySize bytes from body and returns them
+// together with a replacement body that still yields the full stream.
+func (b *bodyCapture) captureRequest(body io.ReadCloser) ([]byte, bool, io.ReadCloser) {
+	prefix, err := io.ReadAll(io.LimitReader(body, int64(b.config.MaxBodySize)+1))
+	replay := struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), body), body}
+	if err != nil {
+		return nil, false, replay
+	}
+
+	if len(prefix) > b.config.MaxBodySize {
+		return prefix[:b.config.MaxBodySize], true, replay
+	}
+	return prefix, false, replay
+}
+
+// rawBody is a captured body that could not be decoded, such as a truncated
+// one. The redactor still masks the key/value pairs it finds in it, and then
+// adds the truncation marker.
+type rawBody struct {
+	text      string
+	truncated bool
+}
+
+// decodeBody turns a captured body into a value for the log entry: decoded
+// JSON or form fields when possible, otherwise the raw text with a marker if
+// truncated.
+func decodeBody(data []byte, truncated bool, contentType string) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	if truncated {
+		return rawBody{text: string(data), truncated: true}
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+		if form, err := url.ParseQuery(string(data)); err == nil {
+			return formFields(form)
+		}
+		return rawBody{text: string(data)}
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err == nil {
+		return value
+	}
+	return rawBody{text: string(data)}
+}
+
+// formFields shapes form values like decoded JSON, so they are redacted the
+// same way. Repeated keys become lists.
+func formFields(form url.Values) map[string]interface{} {
+	fields := make(map[string]interface{}, len(form))
+	for key, values := range form {
+		if len(values) == 1 {
+			fields[key] = values[0]
+			continue
+		}
+		items := make([]interface{}, len(values))
+		for i, value := range values {
+			items[i] = value
+		}
+		fields[key] = items
+	}
+	return fields
+}
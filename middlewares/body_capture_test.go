@@ -0,0 +1,144 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupBodyLoggingRouter(logBuffer *bytes.Buffer, config BodyLoggingConfig) *gin.Engine {
+	logger := slog.New(slog.NewJSONHandler(logBuffer, nil))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(LoggingMiddleware(logger, WithBodyLogging(config)))
+
+	router.POST("/echo", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Data(http.StatusOK, c.ContentType(), body)
+	})
+	router.POST("/upload", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "uploaded"})
+	})
+	return router
+}
+
+func lastLogEntry(t *testing.T, logBuffer *bytes.Buffer) map[string]interface{} {
+	lines := strings.Split(strings.TrimSpace(logBuffer.String()), "\n")
+	var logEntry map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &logEntry))
+	return logEntry
+}
+
+func TestLoggingMiddlewareTruncatesBodies(t *testing.T) {
+	var logBuffer bytes.Buffer
+	config := DefaultBodyLoggingConfig()
+	config.MaxBodySize = 10
+	router := setupBodyLoggingRouter(&logBuffer, config)
+
+	payload := `{"title":"A long title","content":"Long content"}`
+	req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	// The handler and the client still see the whole body.
+	assert.Equal(t, payload, resp.Body.String())
+
+	logEntry := lastLogEntry(t, &logBuffer)
+	assert.Equal(t, `{"title":"`+truncatedMarker, logEntry["RequestBody"])
+	assert.Equal(t, `{"title":"`+truncatedMarker, logEntry["responseBody"])
+}
+
+func TestLoggingMiddlewareRedactsTruncatedAndFormBodies(t *testing.T) {
+	var logBuffer bytes.Buffer
+	config := DefaultBodyLoggingConfig()
+	config.MaxBodySize = 40
+	router := setupBodyLoggingRouter(&logBuffer, config)
+
+	send := func(contentType, payload string) map[string]interface{} {
+		req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(payload))
+		req.Header.Set("Content-Type", contentType)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		return lastLogEntry(t, &logBuffer)
+	}
+
+	logEntry := send("application/json", `{"title":"A","apiKey":"key-0123456789abcdef","content":"`+strings.Repeat("x", 100)+`"}`)
+	assert.Equal(t, `{"title":"A","apiKey":"******`+truncatedMarker, logEntry["RequestBody"])
+	assert.Equal(t, logEntry["RequestBody"], logEntry["responseBody"])
+
+	logEntry = send("application/x-www-form-urlencoded", "user=jane&password=hunter2")
+	assert.Equal(t, map[string]interface{}{"user": "jane", "password": "******"}, logEntry["RequestBody"])
+	assert.Equal(t, logEntry["RequestBody"], logEntry["responseBody"])
+
+	logEntry = send("application/x-www-form-urlencoded", "user=jane&password=hunter2&content="+strings.Repeat("x", 100))
+	assert.Equal(t, "user=jane&password=******&content=xxxxx"+truncatedMarker, logEntry["RequestBody"])
+
+	assert.NotContains(t, logBuffer.String(), "hunter2")
+	assert.NotContains(t, logBuffer.String(), "0123456789abcdef")
+}
+
+func TestLoggingMiddlewareSkipsBinaryBodies(t *testing.T) {
+	var logBuffer bytes.Buffer
+	router := setupBodyLoggingRouter(&logBuffer, DefaultBodyLoggingConfig())
+
+	req := httptest.NewRequest(http.MethodPost, "/echo", bytes.NewReader([]byte{0x89, 'P', 'N', 'G'}))
+	req.Header.Set("Content-Type", "image/png")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 4, resp.Body.Len())
+
+	logEntry := lastLogEntry(t, &logBuffer)
+	assert.Nil(t, logEntry["RequestBody"])
+	assert.Nil(t, logEntry["responseBody"])
+}
+
+func TestLoggingMiddlewareSkipRoutes(t *testing.T) {
+	var logBuffer bytes.Buffer
+	config := DefaultBodyLoggingConfig()
+	config.SkipRoutes = []string{"POST /upload"}
+	router := setupBodyLoggingRouter(&logBuffer, config)
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(`{"file":"data"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	logEntry := lastLogEntry(t, &logBuffer)
+	assert.Equal(t, "/upload", logEntry["path"])
+	assert.Nil(t, logEntry["RequestBody"])
+	assert.Nil(t, logEntry["responseBody"])
+}
+
+func TestBodyCaptureSampling(t *testing.T) {
+	config := DefaultBodyLoggingConfig()
+	config.SampleRate = 0.25
+	capture := newBodyCapture(config)
+
+	capture.sample = func() float64 { return 0.1 }
+	assert.True(t, capture.enabled("POST /articles/create"))
+
+	capture.sample = func() float64 { return 0.5 }
+	assert.False(t, capture.enabled("POST /articles/create"))
+
+	config.SampleRate = 0
+	assert.False(t, newBodyCapture(config).enabled("POST /articles/create"))
+}
+
+func TestBodyCaptureContentTypes(t *testing.T) {
+	capture := newBodyCapture(DefaultBodyLoggingConfig())
+
+	assert.True(t, capture.allowedContentType(""))
+	assert.True(t, capture.allowedContentType("application/json; charset=utf-8"))
+	assert.True(t, capture.allowedContentType("text/csv"))
+	assert.False(t, capture.allowedContentType("application/octet-stream"))
+	assert.False(t, capture.allowedContentType("image/png"))
+}
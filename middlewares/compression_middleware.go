@@ -0,0 +1,279 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressionEncodings lists the supported content codings in the default
+// order of preference.
+var CompressionEncodings = []string{"zstd", "br", "gzip"}
+
+type CompressionConfig struct {
+	// Encodings are the content codings offered, in order of preference when
+	// the client accepts several with the same weight. Empty disables
+	// compression.
+	Encodings []string
+	// MinSize is the smallest body, in bytes, worth compressing. Smaller
+	// responses are sent as is.
+	MinSize int
+	// ContentTypes lists the compressible media types. Entries ending in "/"
+	// match a whole family, e.g. "text/".
+	ContentTypes []string
+}
+
+func DefaultCompressionConfig() CompressionConfig {
+	return CompressionConfig{
+		Encodings:    CompressionEncodings,
+		MinSize:      1024,
+		ContentTypes: []string{"application/json", "application/xml", "application/x-ndjson", "text/"},
+	}
+}
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// newEncoderPool returns a pool of reusable encoders for encoding, or nil if
+// the coding is not supported.
+func newEncoderPool(encoding string) *sync.Pool {
+	var newEncoder func() encoder
+	switch encoding {
+	case "gzip":
+		newEncoder = func() encoder { return gzip.NewWriter(io.Discard) }
+	case "br":
+		newEncoder = func() encoder { return brotli.NewWriterLevel(io.Discard, 5) }
+	case "zstd":
+		newEncoder = func() encoder {
+			// The options are fixed and valid, so NewWriter cannot fail.
+			enc, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+			return enc
+		}
+	default:
+		return nil
+	}
+	return &sync.Pool{New: func() interface{} { return newEncoder() }}
+}
+
+// negotiateEncoding picks the coding to use from an Accept-Encoding header:
+// the supported coding with the highest weight, ties going to the earlier
+// entry in supported. It returns "" when the body should not be encoded.
+func negotiateEncoding(acceptEncoding string, supported []string) string {
+	weights := map[string]float64{}
+	for _, entry := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(entry, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		weight := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(q), 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+		weights[name] = weight
+	}
+
+	best, bestWeight := "", 0.0
+	for _, encoding := range supported {
+		weight, exists := weights[encoding]
+		if !exists {
+			weight = weights["*"]
+		}
+		if weight > bestWeight {
+			best, bestWeight = encoding, weight
+		}
+	}
+	return best
+}
+
+// compressWriter holds back the start of the body until MinSize bytes have
+// been written, then either streams the rest through an encoder or, for small
+// and incompressible responses, sends it unchanged.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding     string
+	pool         *sync.Pool
+	minSize      int
+	contentTypes []string
+
+	buf     bytes.Buffer
+	enc     encoder
+	decided bool
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		if !w.compressible() {
+			if err := w.decide(false); err != nil {
+				return 0, err
+			}
+		} else {
+			w.buf.Write(b)
+			if w.buf.Len() < w.minSize {
+				return len(b), nil
+			}
+			return len(b), w.decide(true)
+		}
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Unwrap lets http.ResponseController reach the connection, e.g. to lift
+// the write deadline of a long-lived stream.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// WriteHeaderNow is deferred until the encoding is decided, because the
+// Content-Encoding header cannot be added once the headers are sent.
+func (w *compressWriter) WriteHeaderNow() {
+	if w.decided {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(w.buf.Len() >= w.minSize && w.compressible())
+	}
+	if w.enc != nil {
+		_ = w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// compressible reports whether the response, as described by the status and
+// headers set so far, may be encoded.
+func (w *compressWriter) compressible() bool {
+	status := w.Status()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length < w.minSize {
+		return false
+	}
+	return matchContentType(header.Get("Content-Type"), w.contentTypes)
+}
+
+// decide sends the headers and the buffered start of the body, encoded or not.
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	if compress {
+		w.Header().Set("Content-Encoding", w.encoding)
+		w.Header().Del("Content-Length")
+		w.enc = w.pool.Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+	if w.buf.Len() == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return nil
+	}
+
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+func (w *compressWriter) close() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.enc != nil {
+		_ = w.enc.Close()
+	}
+	w.release()
+}
+
+// release returns the encoder to its pool and drops the held back body
+// without writing anything.
+func (w *compressWriter) release() {
+	if w.enc != nil {
+		w.enc.Reset(io.Discard)
+		w.pool.Put(w.enc)
+		w.enc = nil
+	}
+	w.buf.Reset()
+}
+
+// CompressionMiddleware encodes response bodies with the best coding the
+// client accepts. It must be registered before LoggingMiddleware so that the
+// logged response body is the uncompressed one.
+func CompressionMiddleware(config CompressionConfig) gin.HandlerFunc {
+	var encodings []string
+	pools := map[string]*sync.Pool{}
+	for _, encoding := range config.Encodings {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if pool := newEncoderPool(encoding); pool != nil {
+			encodings = append(encodings, encoding)
+			pools[encoding] = pool
+		}
+	}
+
+	return func(c *gin.Context) {
+		if len(encodings) == 0 || c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"), encodings)
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		writer := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       encoding,
+			pool:           pools[encoding],
+			minSize:        config.MinSize,
+			contentTypes:   config.ContentTypes,
+		}
+		c.Writer = writer
+		// When a handler panics, nothing is sent here so that the recovery
+		// middleware can still write its 500.
+		completed := false
+		defer func() {
+			c.Writer = writer.ResponseWriter
+			if !completed {
+				writer.release()
+				return
+			}
+			writer.close()
+		}()
+
+		c.Next()
+		completed = true
+	}
+}
@@ -0,0 +1,175 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCompressionRouter(config CompressionConfig, middleware ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CompressionMiddleware(config))
+	router.Use(middleware...)
+	router.GET("/articles", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"content": strings.Repeat("long article content ", 200)})
+	})
+	router.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": 1})
+	})
+	router.GET("/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", bytes.Repeat([]byte{0x89}, 4096))
+	})
+	return router
+}
+
+func getWithEncoding(router *gin.Engine, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func decompress(t *testing.T, encoding string, body []byte) string {
+	var reader io.Reader
+	switch encoding {
+	case "gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		assert.NoError(t, err)
+		reader = gz
+	case "br":
+		reader = brotli.NewReader(bytes.NewReader(body))
+	case "zstd":
+		dec, err := zstd.NewReader(bytes.NewReader(body))
+		assert.NoError(t, err)
+		defer dec.Close()
+		reader = dec
+	default:
+		return string(body)
+	}
+	data, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	supported := []string{"zstd", "br", "gzip"}
+
+	assert.Equal(t, "zstd", negotiateEncoding("gzip, deflate, br, zstd", supported))
+	assert.Equal(t, "gzip", negotiateEncoding("gzip", supported))
+	assert.Equal(t, "br", negotiateEncoding("gzip;q=0.5, br;q=0.8", supported))
+	assert.Equal(t, "br", negotiateEncoding("zstd;q=0, *", supported))
+	assert.Equal(t, "", negotiateEncoding("identity", supported))
+	assert.Equal(t, "", negotiateEncoding("*;q=0", supported))
+	assert.Equal(t, "", negotiateEncoding("", supported))
+	assert.Equal(t, "gzip", negotiateEncoding("GZIP; q=0.3", supported))
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	router := setupCompressionRouter(DefaultCompressionConfig())
+	expected := strings.Repeat("long article content ", 200)
+
+	for _, encoding := range []string{"gzip", "br", "zstd"} {
+		resp := getWithEncoding(router, "/articles", encoding)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, encoding, resp.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", resp.Header().Get("Vary"))
+		assert.Less(t, resp.Body.Len(), len(expected))
+
+		var body map[string]string
+		assert.NoError(t, json.Unmarshal([]byte(decompress(t, encoding, resp.Body.Bytes())), &body))
+		assert.Equal(t, expected, body["content"])
+	}
+}
+
+func TestCompressionMiddlewareSkips(t *testing.T) {
+	router := setupCompressionRouter(DefaultCompressionConfig())
+
+	resp := getWithEncoding(router, "/small", "gzip")
+	assert.Empty(t, resp.Header().Get("Content-Encoding"))
+	assert.JSONEq(t, `{"id":1}`, resp.Body.String())
+
+	resp = getWithEncoding(router, "/image", "gzip")
+	assert.Empty(t, resp.Header().Get("Content-Encoding"))
+	assert.Equal(t, 4096, resp.Body.Len())
+
+	resp = getWithEncoding(router, "/articles", "")
+	assert.Empty(t, resp.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", resp.Header().Get("Vary"))
+
+	router = setupCompressionRouter(CompressionConfig{})
+	resp = getWithEncoding(router, "/articles", "gzip")
+	assert.Empty(t, resp.Header().Get("Content-Encoding"))
+	assert.Empty(t, resp.Header().Get("Vary"))
+}
+
+func TestCompressionMiddlewarePanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gin.RecoveryWithWriter(io.Discard), CompressionMiddleware(DefaultCompressionConfig()))
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	router.GET("/partial", func(c *gin.Context) {
+		c.Header("Content-Type", "application/json")
+		_, _ = c.Writer.WriteString(`{"partial":`)
+		panic("boom")
+	})
+
+	for _, path := range []string{"/panic", "/partial"} {
+		resp := getWithEncoding(router, path, "gzip")
+		assert.Equal(t, http.StatusInternalServerError, resp.Code, path)
+		assert.Empty(t, resp.Header().Get("Content-Encoding"), path)
+		assert.Empty(t, resp.Body.String(), path)
+	}
+}
+
+func TestCompressionMiddlewareLogsUncompressedBody(t *testing.T) {
+	var logBuffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logBuffer, nil))
+	router := setupCompressionRouter(DefaultCompressionConfig(), LoggingMiddleware(logger))
+
+	resp := getWithEncoding(router, "/articles", "gzip")
+	assert.Equal(t, "gzip", resp.Header().Get("Content-Encoding"))
+
+	var logEntry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(logBuffer.Bytes(), &logEntry))
+	responseBody, ok := logEntry["responseBody"].(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, strings.Repeat("long article content ", 200), responseBody["content"])
+}
+
+func TestLoggingMiddlewareSkipsEncodedBody(t *testing.T) {
+	var logBuffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logBuffer, nil))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(LoggingMiddleware(logger))
+	router.Use(CompressionMiddleware(DefaultCompressionConfig()))
+	router.GET("/articles", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"content": strings.Repeat("long article content ", 200)})
+	})
+
+	resp := getWithEncoding(router, "/articles", "gzip")
+	assert.Equal(t, "gzip", resp.Header().Get("Content-Encoding"))
+
+	var logEntry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(logBuffer.Bytes(), &logEntry))
+	assert.Nil(t, logEntry["responseBody"])
+}
@@ -0,0 +1,161 @@
+package middlewares
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CORSConfig struct {
+	// AllowedOrigins lists origins such as "https://app.example.com". An entry
+	// may use a single "*" wildcard, e.g. "https://*.example.com", and "*"
+	// alone allows every origin.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", "X-Request-ID", "Idempotency-Key"},
+		ExposedHeaders: []string{"X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "Idempotent-Replayed"},
+		MaxAge:         10 * time.Minute,
+	}
+}
+
+type originPattern struct {
+	prefix, suffix string
+	wildcard       bool
+}
+
+func (p originPattern) matches(origin string) bool {
+	if !p.wildcard {
+		return origin == p.prefix
+	}
+	return len(origin) > len(p.prefix)+len(p.suffix) &&
+		strings.HasPrefix(origin, p.prefix) && strings.HasSuffix(origin, p.suffix)
+}
+
+// OriginMatcher returns a function reporting whether an Origin header value
+// is one of origins, written as for CORSConfig.AllowedOrigins.
+func OriginMatcher(origins []string) func(origin string) bool {
+	allowAll := false
+	var patterns []originPattern
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		if origin == "*" {
+			allowAll = true
+			continue
+		}
+		prefix, suffix, wildcard := strings.Cut(origin, "*")
+		patterns = append(patterns, originPattern{prefix: prefix, suffix: suffix, wildcard: wildcard})
+	}
+
+	return func(origin string) bool {
+		if allowAll {
+			return true
+		}
+		origin = strings.ToLower(origin)
+		for _, pattern := range patterns {
+			if pattern.matches(origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// CORSMiddleware answers preflight requests and adds CORS headers to
+// responses for allowed origins. Requests from other origins get no CORS
+// headers, and their preflights are rejected with 403.
+func CORSMiddleware(config CORSConfig) gin.HandlerFunc {
+	originAllowed := OriginMatcher(config.AllowedOrigins)
+	allowAll := slices.Contains(config.AllowedOrigins, "*")
+
+	allowedMethods := map[string]bool{}
+	for _, method := range config.AllowedMethods {
+		allowedMethods[strings.ToUpper(method)] = true
+	}
+	allowedHeaders := map[string]bool{}
+	for _, header := range config.AllowedHeaders {
+		allowedHeaders[strings.ToLower(header)] = true
+	}
+
+	methods := strings.Join(config.AllowedMethods, ", ")
+	headers := strings.Join(config.AllowedHeaders, ", ")
+	exposed := strings.Join(config.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if !originAllowed(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		// Any origin is answered with a literal "*", which browsers never
+		// combine with credentials. Echoing the origin instead would let every
+		// site make credentialed reads, so credentials are only allowed for
+		// listed origins.
+		if allowAll {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+			if config.AllowCredentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if !preflight {
+			if exposed != "" {
+				c.Header("Access-Control-Expose-Headers", exposed)
+			}
+			c.Next()
+			return
+		}
+
+		if !allowedMethods[strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))] {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		for _, header := range strings.Split(c.GetHeader("Access-Control-Request-Headers"), ",") {
+			header = strings.ToLower(strings.TrimSpace(header))
+			if header != "" && !allowedHeaders[header] {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+		}
+
+		c.Header("Access-Control-Allow-Methods", methods)
+		if headers != "" {
+			c.Header("Access-Control-Allow-Headers", headers)
+		}
+		c.Header("Access-Control-Max-Age", maxAge)
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
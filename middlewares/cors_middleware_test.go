@@ -0,0 +1,127 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCORSRouter(config CORSConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORSMiddleware(config))
+	router.POST("/articles/create", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+	router.GET("/articles/search", func(c *gin.Context) {
+		c.JSON(http.StatusOK, []gin.H{})
+	})
+	return router
+}
+
+func preflight(router *gin.Engine, origin, method, headers string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, "/articles/create", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		req.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestCORSMiddlewarePreflight(t *testing.T) {
+	config := DefaultCORSConfig()
+	config.AllowedOrigins = []string{"https://app.example.com"}
+	router := setupCORSRouter(config)
+
+	resp := preflight(router, "https://app.example.com", "POST", "Content-Type, X-Request-ID")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "https://app.example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST, PUT, DELETE", resp.Header().Get("Access-Control-Allow-Methods"))
+	assert.Contains(t, resp.Header().Get("Access-Control-Allow-Headers"), "Content-Type")
+	assert.Equal(t, "600", resp.Header().Get("Access-Control-Max-Age"))
+	assert.Contains(t, resp.Header().Values("Vary"), "Origin")
+
+	resp = preflight(router, "https://app.example.com", "PATCH", "")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	resp = preflight(router, "https://app.example.com", "POST", "X-Custom")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestCORSMiddlewareDisallowedOrigin(t *testing.T) {
+	config := DefaultCORSConfig()
+	config.AllowedOrigins = []string{"https://app.example.com"}
+	router := setupCORSRouter(config)
+
+	resp := preflight(router, "https://evil.example.net", "POST", "")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Empty(t, resp.Header().Get("Access-Control-Allow-Origin"))
+
+	req := httptest.NewRequest(http.MethodGet, "/articles/search", nil)
+	req.Header.Set("Origin", "https://evil.example.net")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, resp.Header().Get("Access-Control-Expose-Headers"))
+}
+
+func TestCORSMiddlewareWildcards(t *testing.T) {
+	config := DefaultCORSConfig()
+	config.AllowedOrigins = []string{"https://*.example.com"}
+	config.AllowCredentials = true
+	router := setupCORSRouter(config)
+
+	req := httptest.NewRequest(http.MethodGet, "/articles/search", nil)
+	req.Header.Set("Origin", "https://admin.example.com")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, "https://admin.example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", resp.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Contains(t, resp.Header().Get("Access-Control-Expose-Headers"), "X-Request-ID")
+
+	assert.Equal(t, http.StatusForbidden, preflight(router, "https://example.com", "POST", "").Code)
+	assert.Equal(t, http.StatusForbidden, preflight(router, "https://admin.example.com.evil.net", "POST", "").Code)
+
+	config.AllowedOrigins = []string{"*"}
+	config.AllowCredentials = false
+	router = setupCORSRouter(config)
+
+	resp = preflight(router, "https://anything.test", "GET", "")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "*", resp.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSMiddlewareAnyOriginWithoutCredentials(t *testing.T) {
+	config := DefaultCORSConfig()
+	config.AllowedOrigins = []string{"*"}
+	config.AllowCredentials = true
+	router := setupCORSRouter(config)
+
+	req := httptest.NewRequest(http.MethodGet, "/articles/search", nil)
+	req.Header.Set("Origin", "https://evil.test")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, "*", resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, resp.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestCORSMiddlewareSameOrigin(t *testing.T) {
+	router := setupCORSRouter(DefaultCORSConfig())
+
+	req := httptest.NewRequest(http.MethodGet, "/articles/search", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get("Vary"))
+}
@@ -0,0 +1,323 @@
+package middlewares
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/brothergiez/restful-api/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	MaxIdempotencyKeyLength  = 255
+)
+
+// transportHeaders describe how a response is encoded on the wire rather than
+// the response itself. The stored body is the one the handler wrote, before
+// CompressionMiddleware, so these are set afresh on replay.
+var transportHeaders = map[string]bool{
+	"Content-Encoding":  true,
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+	"Vary":              true,
+}
+
+var (
+	// ErrIdempotencyKeyReused is returned by Reserve when the key was used
+	// before for a different request.
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+	// ErrIdempotencyKeyInFlight is returned by Reserve while the first
+	// request with the key is still being handled.
+	ErrIdempotencyKeyInFlight = errors.New("idempotency key in flight")
+	// ErrIdempotencyStoreFull is returned by Reserve when no key can be
+	// dropped to make room because every key held is still in flight.
+	ErrIdempotencyStoreFull = errors.New("idempotency store full")
+)
+
+type IdempotencyConfig struct {
+	// TTL is how long a key and its response are kept.
+	TTL time.Duration
+	// MaxBodySize is the largest request body, in bytes, accepted with an
+	// Idempotency-Key. The body is read in full to fingerprint it.
+	MaxBodySize int64
+	// MaxEntries caps the keys held by the memory store. When it is reached
+	// the completed key closest to expiring is dropped; keys still in flight
+	// are never dropped.
+	MaxEntries int
+}
+
+func DefaultIdempotencyConfig() IdempotencyConfig {
+	return IdempotencyConfig{
+		TTL:         24 * time.Hour,
+		MaxBodySize: 1 << 20,
+		MaxEntries:  10000,
+	}
+}
+
+// IdempotentResponse is what is replayed for a repeated request.
+type IdempotentResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyStore keeps the keys. Reserve claims key for a request with the
+// given fingerprint: it returns nil for a new key, the stored response for a
+// completed one, or ErrIdempotencyKeyReused, ErrIdempotencyKeyInFlight or
+// ErrIdempotencyStoreFull.
+// A reserved key is then either completed with the response or released so
+// the request can be retried.
+type IdempotencyStore interface {
+	Reserve(key, fingerprint string, now time.Time) (*IdempotentResponse, error)
+	Complete(key string, response IdempotentResponse, now time.Time)
+	Release(key string)
+}
+
+type idempotencyEntry struct {
+	key         string
+	fingerprint string
+	response    *IdempotentResponse
+	expires     time.Time
+}
+
+// MemoryIdempotencyStore keeps the keys in a list ordered by expiry, so
+// expired keys are dropped from the front on every call and, once maxEntries
+// is reached, the completed key closest to expiring makes room for a new one.
+type MemoryIdempotencyStore struct {
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List
+	ttl        time.Duration
+	maxEntries int
+}
+
+// NewMemoryIdempotencyStore returns a store that keeps keys for ttl and holds
+// at most maxEntries of them; zero means no limit.
+func NewMemoryIdempotencyStore(ttl time.Duration, maxEntries int) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		entries:    map[string]*list.Element{},
+		order:      list.New(),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+	}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(key, fingerprint string, now time.Time) (*IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictExpired(now)
+
+	element, exists := s.entries[key]
+	if !exists {
+		for s.maxEntries > 0 && s.order.Len() >= s.maxEntries {
+			oldest := s.oldestCompleted()
+			if oldest == nil {
+				return nil, ErrIdempotencyStoreFull
+			}
+			s.remove(oldest)
+		}
+		entry := &idempotencyEntry{key: key, fingerprint: fingerprint, expires: now.Add(s.ttl)}
+		s.entries[key] = s.order.PushBack(entry)
+		return nil, nil
+	}
+	entry := element.Value.(*idempotencyEntry)
+	if entry.fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if entry.response == nil {
+		return nil, ErrIdempotencyKeyInFlight
+	}
+	return entry.response, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(key string, response IdempotentResponse, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, exists := s.entries[key]; exists {
+		entry := element.Value.(*idempotencyEntry)
+		entry.response = &response
+		entry.expires = now.Add(s.ttl)
+		s.order.MoveToBack(element)
+	}
+}
+
+func (s *MemoryIdempotencyStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, exists := s.entries[key]; exists {
+		s.remove(element)
+	}
+}
+
+// Len returns the number of keys currently held in memory.
+func (s *MemoryIdempotencyStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// evictExpired drops expired keys from the front of the list. It only looks
+// at the keys it removes, plus one, so it runs on every call.
+func (s *MemoryIdempotencyStore) evictExpired(now time.Time) {
+	for front := s.order.Front(); front != nil; front = s.order.Front() {
+		if now.Before(front.Value.(*idempotencyEntry).expires) {
+			return
+		}
+		s.remove(front)
+	}
+}
+
+// oldestCompleted returns the completed key closest to expiring. Keys in
+// flight are skipped: dropping one would let a retry run the request twice.
+func (s *MemoryIdempotencyStore) oldestCompleted() *list.Element {
+	for element := s.order.Front(); element != nil; element = element.Next() {
+		if element.Value.(*idempotencyEntry).response != nil {
+			return element
+		}
+	}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) remove(element *list.Element) {
+	delete(s.entries, element.Value.(*idempotencyEntry).key)
+	s.order.Remove(element)
+}
+
+// recordingWriter keeps a copy of the whole response so it can be replayed.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Unwrap gives http.ResponseController access to the wrapped writer.
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// fingerprint identifies a request by method, URI and body, so the same key
+// sent to another route or with another body is detected.
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// changedHeaders returns the headers the handler set, leaving out those that
+// were already present, such as X-Request-ID, which belong to each request,
+// and the transport headers.
+func changedHeaders(before, after http.Header) http.Header {
+	changed := http.Header{}
+	for key, values := range after {
+		if !transportHeaders[key] && !slices.Equal(before[key], values) {
+			changed[key] = slices.Clone(values)
+		}
+	}
+	return changed
+}
+
+// IdempotencyMiddleware makes POST requests that carry an Idempotency-Key
+// safe to retry. The first request with a key runs normally and its response
+// is stored; a retry with the same key and request gets that response again
+// with Idempotent-Replayed set. Keys are scoped per client, like rate limits.
+// Server errors are not stored, so a request that failed that way can be
+// retried with the same key.
+func IdempotencyMiddleware(config IdempotencyConfig, store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > MaxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, requestid.ErrorBody(c, "Idempotency-Key must be at most 255 characters"))
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(io.LimitReader(c.Request.Body, config.MaxBodySize+1))
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, requestid.ErrorBody(c, "Failed to read request body"))
+				return
+			}
+			if int64(len(body)) > config.MaxBodySize {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, requestid.ErrorBody(c, "Request body is too large to be sent with an Idempotency-Key"))
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		storeKey := clientKey(c) + "|" + key
+		stored, err := store.Reserve(storeKey, fingerprint(c.Request, body), time.Now())
+		switch {
+		case errors.Is(err, ErrIdempotencyKeyReused):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, requestid.ErrorBody(c, "Idempotency-Key was already used for a different request"))
+			return
+		case errors.Is(err, ErrIdempotencyKeyInFlight):
+			c.AbortWithStatusJSON(http.StatusConflict, requestid.ErrorBody(c, "A request with this Idempotency-Key is still being processed"))
+			return
+		case errors.Is(err, ErrIdempotencyStoreFull):
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, requestid.ErrorBody(c, "Too many requests with an Idempotency-Key are being processed"))
+			return
+		case stored != nil:
+			for name, values := range stored.Header {
+				c.Writer.Header()[name] = slices.Clone(values)
+			}
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Status(stored.Status)
+			_, _ = c.Writer.Write(stored.Body)
+			c.Abort()
+			return
+		}
+
+		completed := false
+		defer func() {
+			// Also runs when the handler panics, so the key is not left
+			// reserved forever.
+			if !completed {
+				store.Release(storeKey)
+			}
+		}()
+
+		headersBefore := c.Writer.Header().Clone()
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		status := c.Writer.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		store.Complete(storeKey, IdempotentResponse{
+			Status: status,
+			Header: changedHeaders(headersBefore, c.Writer.Header()),
+			Body:   writer.body.Bytes(),
+		}, time.Now())
+		completed = true
+	}
+}
@@ -0,0 +1,260 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupIdempotencyRouter(calls *atomic.Int32, middleware ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware...)
+	router.Use(IdempotencyMiddleware(DefaultIdempotencyConfig(), NewMemoryIdempotencyStore(time.Hour, 0)))
+	router.POST("/articles/create", func(c *gin.Context) {
+		n := calls.Add(1)
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Header("Location", "/articles/"+strconv.Itoa(int(n)))
+		c.JSON(http.StatusCreated, gin.H{"id": n, "body": string(body)})
+	})
+	router.POST("/articles/fail", func(c *gin.Context) {
+		calls.Add(1)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "boom"})
+	})
+	router.GET("/articles/search", func(c *gin.Context) {
+		calls.Add(1)
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	return router
+}
+
+func sendIdempotent(router *gin.Engine, method, path, key, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestIdempotencyMiddlewareReplay(t *testing.T) {
+	var calls atomic.Int32
+	router := setupIdempotencyRouter(&calls, RequestIDMiddleware())
+
+	first := sendIdempotent(router, http.MethodPost, "/articles/create", "key-1", `{"title":"A"}`)
+	second := sendIdempotent(router, http.MethodPost, "/articles/create", "key-1", `{"title":"A"}`)
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "/articles/1", second.Header().Get("Location"))
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+	assert.NotEqual(t, first.Header().Get("X-Request-ID"), second.Header().Get("X-Request-ID"))
+
+	third := sendIdempotent(router, http.MethodPost, "/articles/create", "key-2", `{"title":"A"}`)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Contains(t, third.Body.String(), `"id":2`)
+
+	sendIdempotent(router, http.MethodPost, "/articles/create", "", `{"title":"A"}`)
+	sendIdempotent(router, http.MethodGet, "/articles/search", "key-1", "")
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestIdempotencyMiddlewareMismatch(t *testing.T) {
+	var calls atomic.Int32
+	router := setupIdempotencyRouter(&calls)
+
+	sendIdempotent(router, http.MethodPost, "/articles/create", "key-1", `{"title":"A"}`)
+	resp := sendIdempotent(router, http.MethodPost, "/articles/create", "key-1", `{"title":"B"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Contains(t, resp.Body.String(), "different request")
+
+	resp = sendIdempotent(router, http.MethodPost, "/articles/create?draft=true", "key-1", `{"title":"A"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestIdempotencyMiddlewareScopedPerClient(t *testing.T) {
+	var calls atomic.Int32
+	router := setupIdempotencyRouter(&calls, APIKeyIdentityMiddleware([]string{"client-a-0123456789", "client-b-0123456789"}))
+
+	sendIdempotent(router, http.MethodPost, "/articles/create", "key-1", `{}`, "X-API-Key", "client-a-0123456789")
+	resp := sendIdempotent(router, http.MethodPost, "/articles/create", "key-1", `{}`, "X-API-Key", "client-b-0123456789")
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Empty(t, resp.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestIdempotencyMiddlewareInFlight(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(IdempotencyMiddleware(DefaultIdempotencyConfig(), NewMemoryIdempotencyStore(time.Hour, 0)))
+	router.POST("/articles/create", func(c *gin.Context) {
+		close(started)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- sendIdempotent(router, http.MethodPost, "/articles/create", "key-1", `{}`)
+	}()
+	<-started
+
+	resp := sendIdempotent(router, http.MethodPost, "/articles/create", "key-1", `{}`)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	close(release)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+}
+
+func TestIdempotencyMiddlewareStoreFull(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(IdempotencyMiddleware(DefaultIdempotencyConfig(), NewMemoryIdempotencyStore(time.Hour, 1)))
+	router.POST("/articles/create", func(c *gin.Context) {
+		close(started)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- sendIdempotent(router, http.MethodPost, "/articles/create", "key-1", `{}`)
+	}()
+	<-started
+
+	resp := sendIdempotent(router, http.MethodPost, "/articles/create", "key-2", `{}`)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+
+	close(release)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+	resp = sendIdempotent(router, http.MethodPost, "/articles/create", "key-1", `{}`)
+	assert.Equal(t, "true", resp.Header().Get(IdempotentReplayedHeader))
+}
+
+func TestIdempotencyMiddlewareServerErrorsAreRetryable(t *testing.T) {
+	var calls atomic.Int32
+	router := setupIdempotencyRouter(&calls)
+
+	sendIdempotent(router, http.MethodPost, "/articles/fail", "key-1", `{}`)
+	resp := sendIdempotent(router, http.MethodPost, "/articles/fail", "key-1", `{}`)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Empty(t, resp.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestIdempotencyMiddlewareLimits(t *testing.T) {
+	var calls atomic.Int32
+	router := setupIdempotencyRouter(&calls)
+
+	resp := sendIdempotent(router, http.MethodPost, "/articles/create", strings.Repeat("k", 256), `{}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp = sendIdempotent(router, http.MethodPost, "/articles/create", "key-1", strings.Repeat("x", 1<<20+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+	assert.Equal(t, int32(0), calls.Load())
+}
+
+func TestIdempotencyMiddlewareReplayIsCompressedAgain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CompressionMiddleware(DefaultCompressionConfig()))
+	router.Use(IdempotencyMiddleware(DefaultIdempotencyConfig(), NewMemoryIdempotencyStore(time.Hour, 0)))
+	content := strings.Repeat("long article content ", 200)
+	router.POST("/articles/create", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"content": content})
+	})
+
+	sendIdempotent(router, http.MethodPost, "/articles/create", "key-1", `{}`, "Accept-Encoding", "gzip")
+	resp := sendIdempotent(router, http.MethodPost, "/articles/create", "key-1", `{}`)
+
+	assert.Equal(t, "true", resp.Header().Get(IdempotentReplayedHeader))
+	assert.Empty(t, resp.Header().Get("Content-Encoding"))
+	assert.Contains(t, resp.Body.String(), content)
+
+	resp = sendIdempotent(router, http.MethodPost, "/articles/create", "key-1", `{}`, "Accept-Encoding", "gzip")
+	assert.Equal(t, "gzip", resp.Header().Get("Content-Encoding"))
+	reader, err := gzip.NewReader(bytes.NewReader(resp.Body.Bytes()))
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(reader)
+	assert.Contains(t, string(body), content)
+}
+
+func TestMemoryIdempotencyStoreExpiry(t *testing.T) {
+	store := NewMemoryIdempotencyStore(time.Minute, 0)
+	now := time.Now()
+
+	stored, err := store.Reserve("key", "fp", now)
+	assert.Nil(t, stored)
+	assert.NoError(t, err)
+	store.Complete("key", IdempotentResponse{Status: http.StatusCreated}, now)
+
+	stored, err = store.Reserve("key", "fp", now.Add(30*time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, stored.Status)
+
+	stored, err = store.Reserve("key", "other", now.Add(2*time.Minute))
+	assert.Nil(t, stored)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, store.Len())
+
+	store.Reserve("another", "fp", now.Add(5*time.Minute))
+	assert.Equal(t, 1, store.Len())
+}
+
+func TestMemoryIdempotencyStoreMaxEntries(t *testing.T) {
+	store := NewMemoryIdempotencyStore(time.Minute, 2)
+	now := time.Now()
+
+	store.Reserve("a", "fp", now)
+	store.Reserve("b", "fp", now.Add(time.Second))
+	store.Complete("a", IdempotentResponse{Status: http.StatusCreated}, now.Add(2*time.Second))
+	store.Reserve("c", "fp", now.Add(3*time.Second))
+	assert.Equal(t, 2, store.Len())
+
+	// b is closer to expiring but still in flight, so a is the one dropped.
+	_, err := store.Reserve("b", "fp", now.Add(4*time.Second))
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInFlight)
+	_, err = store.Reserve("c", "fp", now.Add(4*time.Second))
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInFlight)
+
+	// Nothing can be dropped while every key is in flight.
+	stored, err := store.Reserve("d", "fp", now.Add(5*time.Second))
+	assert.Nil(t, stored)
+	assert.ErrorIs(t, err, ErrIdempotencyStoreFull)
+	assert.Equal(t, 2, store.Len())
+
+	store.Complete("b", IdempotentResponse{Status: http.StatusCreated}, now.Add(6*time.Second))
+	stored, err = store.Reserve("d", "fp", now.Add(7*time.Second))
+	assert.Nil(t, stored)
+	assert.NoError(t, err)
+	_, err = store.Reserve("c", "fp", now.Add(7*time.Second))
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInFlight)
+	assert.Equal(t, 2, store.Len())
+}
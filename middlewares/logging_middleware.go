@@ -2,86 +2,172 @@ package middlewares
 
 import (
 	"bytes"
-	"encoding/json"
-	"io/ioutil"
-	"log"
+	"log/slog"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-var sensitiveFields = []string{"password", "token", "secret", "id_token"}
+type loggingOptions struct {
+	redactor    *Redactor
+	bodyLogging BodyLoggingConfig
+	skipPaths   map[string]bool
+}
+
+type LoggingOption func(*loggingOptions)
+
+// WithRedactor replaces the default redactor applied to logged bodies and
+// headers.
+func WithRedactor(redactor *Redactor) LoggingOption {
+	return func(o *loggingOptions) {
+		o.redactor = redactor
+	}
+}
 
-func anonymizeSensitiveData(data map[string]interface{}) map[string]interface{} {
-	for _, field := range sensitiveFields {
-		if _, exists := data[field]; exists {
-			data[field] = "******"
+// WithBodyLogging replaces the default limits on captured bodies.
+func WithBodyLogging(config BodyLoggingConfig) LoggingOption {
+	return func(o *loggingOptions) {
+		o.bodyLogging = config
+	}
+}
+
+// WithSkipPaths disables logging for requests to the given paths, such as
+// health probes.
+func WithSkipPaths(paths ...string) LoggingOption {
+	return func(o *loggingOptions) {
+		for _, path := range paths {
+			o.skipPaths[path] = true
 		}
 	}
-	return data
 }
 
+// CustomResponseWriter copies up to maxSize bytes of the response body into
+// body while passing every byte through to the client.
 type CustomResponseWriter struct {
 	gin.ResponseWriter
-	body *bytes.Buffer
+	body      *bytes.Buffer
+	maxSize   int
+	truncated bool
+	allowed   func(contentType string) bool
+	checked   bool
+	skip      bool
 }
 
 func (w *CustomResponseWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	w.capture(b)
 	return w.ResponseWriter.Write(b)
 }
 
-func LoggingMiddleware() gin.HandlerFunc {
+func (w *CustomResponseWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Unwrap gives http.ResponseController access to the wrapped writer.
+func (w *CustomResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *CustomResponseWriter) capture(b []byte) {
+	if !w.checked {
+		w.checked = true
+		// A body that is already encoded would be logged as binary noise.
+		// CompressionMiddleware registered ahead of logging encodes after
+		// this writer, so its output never reaches here.
+		w.skip = w.Header().Get("Content-Encoding") != "" ||
+			w.allowed != nil && !w.allowed(w.Header().Get("Content-Type"))
+	}
+	if w.skip || w.truncated {
+		return
+	}
+
+	room := w.maxSize - w.body.Len()
+	if len(b) > room {
+		w.body.Write(b[:room])
+		w.truncated = true
+		return
+	}
+	w.body.Write(b)
+}
+
+// statusLevel logs server errors at error level, client errors at warn level
+// and everything else at info level.
+func statusLevel(status int) slog.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case status >= http.StatusBadRequest:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// LoggingMiddleware writes one structured entry per request to logger.
+func LoggingMiddleware(logger *slog.Logger, opts ...LoggingOption) gin.HandlerFunc {
+	options := loggingOptions{
+		redactor:    NewRedactor(DefaultRedactionConfig()),
+		bodyLogging: DefaultBodyLoggingConfig(),
+		skipPaths:   map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	capture := newBodyCapture(options.bodyLogging)
+
 	return func(c *gin.Context) {
+		if options.skipPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
 		startTime := time.Now()
+		captureBodies := capture.enabled(c.Request.Method + " " + c.FullPath())
 
-		var requestBody map[string]interface{}
-		if c.Request.Body != nil {
-			bodyBytes, err := ioutil.ReadAll(c.Request.Body)
-			if err == nil {
-				_ = json.Unmarshal(bodyBytes, &requestBody)
-				c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
-			}
+		var requestBody interface{}
+		if captureBodies && c.Request.Body != nil && capture.allowedContentType(c.GetHeader("Content-Type")) {
+			bodyBytes, truncated, body := capture.captureRequest(c.Request.Body)
+			requestBody = decodeBody(bodyBytes, truncated, c.GetHeader("Content-Type"))
+			c.Request.Body = body
 		}
 
-		anonymizedBody := anonymizeSensitiveData(requestBody)
-
-		customWriter := &CustomResponseWriter{
-			ResponseWriter: c.Writer,
-			body:           &bytes.Buffer{},
+		var customWriter *CustomResponseWriter
+		if captureBodies {
+			customWriter = &CustomResponseWriter{
+				ResponseWriter: c.Writer,
+				body:           &bytes.Buffer{},
+				maxSize:        options.bodyLogging.MaxBodySize,
+				allowed:        capture.allowedContentType,
+			}
+			c.Writer = customWriter
 		}
-		c.Writer = customWriter
 
 		c.Next()
 
-		var responseData map[string]interface{}
-		_ = json.Unmarshal(customWriter.body.Bytes(), &responseData)
-
-		anonymizedResponse := anonymizeSensitiveData(responseData)
-
-		requestHeadersJSON, _ := json.Marshal(c.Request.Header)
-		responseHeadersJSON, _ := json.Marshal(c.Writer.Header())
-
-		logEntry := map[string]interface{}{
-			"time":            startTime.Format(time.RFC3339),
-			"level":           "info",
-			"method":          c.Request.Method,
-			"path":            c.Request.URL.Path,
-			"requestHeaders":  json.RawMessage(requestHeadersJSON),
-			"responseHeaders": json.RawMessage(responseHeadersJSON),
-			"RequestBody":     anonymizedBody,
-			"responseBody":    anonymizedResponse,
-			"status":          c.Writer.Status(),
-			"duration_ms":     time.Since(startTime).Milliseconds(),
+		status := c.Writer.Status()
+		level := statusLevel(status)
+		ctx := c.Request.Context()
+		if !logger.Enabled(ctx, level) {
+			return
 		}
 
-		logEntryJSON, _ := json.Marshal(logEntry)
-
-		currentFlags := log.Flags()
-		log.SetFlags(0)
-		log.Println()
-		log.SetFlags(currentFlags)
+		var responseData interface{}
+		if customWriter != nil {
+			responseData = decodeBody(customWriter.body.Bytes(), customWriter.truncated, c.Writer.Header().Get("Content-Type"))
+		}
 
-		log.Println(string(logEntryJSON))
+		record := slog.NewRecord(startTime, level, "request", 0)
+		record.AddAttrs(
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Any("requestHeaders", options.redactor.RedactHeaders(c.Request.Header)),
+			slog.Any("responseHeaders", options.redactor.RedactHeaders(c.Writer.Header())),
+			slog.Any("RequestBody", options.redactor.Redact(requestBody)),
+			slog.Any("responseBody", options.redactor.Redact(responseData)),
+			slog.Int("status", status),
+			slog.Int64("duration_ms", time.Since(startTime).Milliseconds()),
+		)
+		_ = logger.Handler().Handle(ctx, record)
 	}
 }
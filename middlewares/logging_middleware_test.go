@@ -3,26 +3,27 @@ package middlewares
 import (
 	"bytes"
 	"encoding/json"
-	"io/ioutil"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/brothergiez/restful-api/logger"
+	"github.com/brothergiez/restful-api/requestid"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestLoggingMiddleware(t *testing.T) {
 	var logBuffer bytes.Buffer
-	log.SetOutput(&logBuffer)
-	defer log.SetOutput(ioutil.Discard)
+	logger := slog.New(slog.NewJSONHandler(&logBuffer, nil))
 
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
-	router.Use(LoggingMiddleware())
+	router.Use(LoggingMiddleware(logger))
 
 	router.POST("/test", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -44,13 +45,15 @@ func TestLoggingMiddleware(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, resp.Code)
 
-	logLines := logBuffer.String()
+	logLines := strings.Split(strings.TrimSpace(logBuffer.String()), "\n")
+	assert.Len(t, logLines, 1)
 
-	logLines = logLines[strings.Index(logLines, "{"):]
 	var logEntry map[string]interface{}
-	err := json.Unmarshal([]byte(logLines), &logEntry)
+	err := json.Unmarshal([]byte(logLines[0]), &logEntry)
 	assert.NoError(t, err)
 
+	assert.Equal(t, "INFO", logEntry["level"])
+	assert.Equal(t, "request", logEntry["msg"])
 	assert.Equal(t, "POST", logEntry["method"])
 	assert.Equal(t, "/test", logEntry["path"])
 	assert.Equal(t, float64(200), logEntry["status"])
@@ -76,3 +79,128 @@ func TestLoggingMiddleware(t *testing.T) {
 	assert.NoError(t, err)
 	assert.WithinDuration(t, startTime, parsedTime, time.Second)
 }
+
+func TestLoggingMiddlewareLevels(t *testing.T) {
+	var logBuffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logBuffer, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(LoggingMiddleware(logger))
+
+	router.GET("/ok", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+	router.GET("/missing", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	assert.Empty(t, logBuffer.String())
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	var logEntry map[string]interface{}
+	err := json.Unmarshal(logBuffer.Bytes(), &logEntry)
+	assert.NoError(t, err)
+	assert.Equal(t, "WARN", logEntry["level"])
+	assert.Equal(t, float64(http.StatusNotFound), logEntry["status"])
+}
+
+func TestLoggingMiddlewareRequestID(t *testing.T) {
+	var logBuffer bytes.Buffer
+	handler, err := logger.NewHandler(&logBuffer, "json", slog.LevelInfo)
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+	router.Use(LoggingMiddleware(slog.New(handler)))
+
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set(requestid.Header, "client-id-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var logEntry map[string]interface{}
+	err = json.Unmarshal(logBuffer.Bytes(), &logEntry)
+	assert.NoError(t, err)
+	assert.Equal(t, "client-id-1", logEntry["request_id"])
+}
+
+func TestLoggingMiddlewarePanic(t *testing.T) {
+	var logBuffer bytes.Buffer
+	handler, err := logger.NewHandler(&logBuffer, "json", slog.LevelInfo)
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+	router.Use(LoggingMiddleware(slog.New(handler)))
+	router.Use(gin.RecoveryWithWriter(io.Discard))
+
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(requestid.Header, "client-id-1")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+
+	var logEntry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(logBuffer.Bytes(), &logEntry))
+	assert.Equal(t, "ERROR", logEntry["level"])
+	assert.Equal(t, float64(http.StatusInternalServerError), logEntry["status"])
+	assert.Equal(t, "client-id-1", logEntry["request_id"])
+}
+
+func TestLoggingMiddlewareRedaction(t *testing.T) {
+	var logBuffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logBuffer, nil))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(LoggingMiddleware(logger))
+
+	router.POST("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, []gin.H{{"email": "jane@example.com"}})
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(`{"user":{"password":"12345"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret-token")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var logEntry map[string]interface{}
+	err := json.Unmarshal(logBuffer.Bytes(), &logEntry)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{"user": map[string]interface{}{"password": "******"}}, logEntry["RequestBody"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"email": "******"}}, logEntry["responseBody"])
+	assert.Equal(t, []interface{}{"******"}, logEntry["requestHeaders"].(map[string]interface{})["Authorization"])
+	assert.NotContains(t, logBuffer.String(), "secret-token")
+}
+
+func TestLoggingMiddlewareSkipPaths(t *testing.T) {
+	var logBuffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logBuffer, nil))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(LoggingMiddleware(logger, WithSkipPaths("/healthz")))
+
+	router.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, logBuffer.String())
+}
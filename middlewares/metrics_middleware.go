@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"time"
+
+	"github.com/brothergiez/restful-api/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsMiddleware records request counts and latency by route template.
+// Requests that match no route are grouped under "unmatched".
+func MetricsMiddleware(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startTime := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(startTime))
+	}
+}
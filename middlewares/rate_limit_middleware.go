@@ -0,0 +1,225 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brothergiez/restful-api/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitRule describes a token bucket: Rate tokens are added per second up
+// to a maximum of Burst tokens. A rule with a zero Rate disables limiting.
+type RateLimitRule struct {
+	Rate  float64
+	Burst int
+}
+
+type RateLimitConfig struct {
+	// Default applies to every route without an entry in Routes.
+	Default RateLimitRule
+	// Routes is keyed by "METHOD /route/template", e.g. "GET /articles/search".
+	Routes map[string]RateLimitRule
+	// IdleTTL is how long an untouched bucket is kept before it is evicted.
+	IdleTTL time.Duration
+}
+
+// ParseRateLimitRoutes parses per-route rules in the "METHOD /path=rate:burst"
+// format used by RATE_LIMIT_ROUTES.
+func ParseRateLimitRoutes(value string) (map[string]RateLimitRule, error) {
+	routes := map[string]RateLimitRule{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, limit, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid rate limit route %q", entry)
+		}
+
+		method, path, found := strings.Cut(strings.TrimSpace(route), " ")
+		if !found || path == "" {
+			return nil, fmt.Errorf("invalid rate limit route %q: expected \"METHOD /path\"", entry)
+		}
+
+		rateStr, burstStr, _ := strings.Cut(limit, ":")
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("invalid rate in rate limit route %q", entry)
+		}
+
+		burst := int(math.Ceil(rate))
+		if burstStr != "" {
+			burst, err = strconv.Atoi(burstStr)
+			if err != nil || burst < 1 {
+				return nil, fmt.Errorf("invalid burst in rate limit route %q", entry)
+			}
+		}
+
+		routes[strings.ToUpper(method)+" "+strings.TrimSpace(path)] = RateLimitRule{Rate: rate, Burst: burst}
+	}
+	return routes, nil
+}
+
+// RateLimitStore keeps the token buckets. Take consumes one token from the
+// bucket identified by key and reports whether the request is allowed, how
+// many tokens remain and how long until the bucket is full again.
+type RateLimitStore interface {
+	Take(key string, rule RateLimitRule, now time.Time) (allowed bool, remaining int, reset time.Duration)
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	idleTTL   time.Duration
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore(idleTTL time.Duration) *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: map[string]*tokenBucket{},
+		idleTTL: idleTTL,
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, rule RateLimitRule, now time.Time) (bool, int, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictIdle(now)
+
+	burst := float64(rule.Burst)
+	bucket, exists := s.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: burst, lastSeen: now}
+		s.buckets[key] = bucket
+	} else {
+		elapsed := now.Sub(bucket.lastSeen).Seconds()
+		bucket.tokens = math.Min(burst, bucket.tokens+elapsed*rule.Rate)
+		bucket.lastSeen = now
+	}
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+
+	reset := time.Duration((burst - bucket.tokens) / rule.Rate * float64(time.Second))
+	return allowed, int(bucket.tokens), reset
+}
+
+// Len returns the number of buckets currently held in memory.
+func (s *MemoryRateLimitStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
+
+// evictIdle drops buckets that have not been used for idleTTL. It runs at most
+// once per idleTTL so the cost is amortised across requests.
+func (s *MemoryRateLimitStore) evictIdle(now time.Time) {
+	if s.idleTTL <= 0 || now.Sub(s.lastSweep) < s.idleTTL {
+		return
+	}
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.lastSeen) >= s.idleTTL {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// APIKeyContextKey is the gin context key holding the API key of a request
+// once it has been checked. Rate limits and idempotency keys are scoped by it.
+const APIKeyContextKey = "apiKey"
+
+// APIKeyMatcher returns a function reporting whether an API key is one of
+// keys. Keys are compared by hash in constant time; an empty key never
+// matches.
+func APIKeyMatcher(keys []string) func(string) bool {
+	hashes := make([][32]byte, len(keys))
+	for i, key := range keys {
+		hashes[i] = sha256.Sum256([]byte(key))
+	}
+
+	return func(apiKey string) bool {
+		provided := sha256.Sum256([]byte(apiKey))
+
+		valid := 0
+		for _, hash := range hashes {
+			valid |= subtle.ConstantTimeCompare(provided[:], hash[:])
+		}
+		return apiKey != "" && valid == 1
+	}
+}
+
+// APIKeyIdentityMiddleware stores an X-API-Key header that matches one of keys
+// under APIKeyContextKey and lets every request through. It runs before the
+// rate limiter, so that only a checked key gives a client a bucket of its own.
+func APIKeyIdentityMiddleware(keys []string) gin.HandlerFunc {
+	valid := APIKeyMatcher(keys)
+
+	return func(c *gin.Context) {
+		if apiKey := c.GetHeader("X-API-Key"); valid(apiKey) {
+			c.Set(APIKeyContextKey, apiKey)
+		}
+
+		c.Next()
+	}
+}
+
+// clientKey identifies the client: the API key once an upstream middleware
+// has checked it, then the authenticated user, then the client IP. The raw
+// header is never used, or every made-up key would get a fresh bucket.
+func clientKey(c *gin.Context) string {
+	if apiKey := c.GetString(APIKeyContextKey); apiKey != "" {
+		return "key:" + apiKey
+	}
+	if user := c.GetString("user"); user != "" {
+		return "user:" + user
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func RateLimitMiddleware(config RateLimitConfig, store RateLimitStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		rule, exists := config.Routes[route]
+		if !exists {
+			rule = config.Default
+		}
+		if rule.Rate <= 0 || rule.Burst < 1 {
+			c.Next()
+			return
+		}
+
+		allowed, remaining, reset := store.Take(route+"|"+clientKey(c), rule, time.Now())
+
+		c.Header("RateLimit-Limit", strconv.Itoa(rule.Burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil(reset.Seconds()))))
+
+		if !allowed {
+			retryAfter := int(math.Ceil(1 / rule.Rate))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, requestid.ErrorBody(c, "Too many requests"))
+			return
+		}
+
+		c.Next()
+	}
+}
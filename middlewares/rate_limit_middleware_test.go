@@ -0,0 +1,155 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRateLimitRouter(config RateLimitConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RateLimitMiddleware(config, NewMemoryRateLimitStore(config.IdleTTL)))
+	router.GET("/articles/search", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+	router.GET("/articles/get-all", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+	return router
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	router := setupRateLimitRouter(RateLimitConfig{
+		Routes: map[string]RateLimitRule{
+			"GET /articles/search": {Rate: 1, Burst: 2},
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/articles/search", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "2", resp.Header().Get("RateLimit-Limit"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/articles/search", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "0", resp.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", resp.Header().Get("Retry-After"))
+
+	// Routes without a rule are not limited.
+	for i := 0; i < 5; i++ {
+		req = httptest.NewRequest(http.MethodGet, "/articles/get-all", nil)
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Empty(t, resp.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestRateLimitMiddlewareKeys(t *testing.T) {
+	config := RateLimitConfig{Default: RateLimitRule{Rate: 1, Burst: 1}}
+	router := gin.New()
+	router.Use(APIKeyIdentityMiddleware([]string{"key-a-0123456789", "key-b-0123456789"}))
+	router.Use(RateLimitMiddleware(config, NewMemoryRateLimitStore(config.IdleTTL)))
+	router.GET("/articles/search", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	send := func(apiKey, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/articles/search", nil)
+		req.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("", "10.0.0.1:1234"))
+	assert.Equal(t, http.StatusTooManyRequests, send("", "10.0.0.1:1234"))
+	assert.Equal(t, http.StatusOK, send("", "10.0.0.2:1234"))
+	assert.Equal(t, http.StatusOK, send("key-a-0123456789", "10.0.0.1:1234"))
+	assert.Equal(t, http.StatusOK, send("key-b-0123456789", "10.0.0.1:1234"))
+	assert.Equal(t, http.StatusTooManyRequests, send("key-a-0123456789", "10.0.0.2:1234"))
+
+	// Keys that were not checked share the bucket of the client IP.
+	assert.Equal(t, http.StatusTooManyRequests, send("made-up-1", "10.0.0.2:1234"))
+	assert.Equal(t, http.StatusTooManyRequests, send("made-up-2", "10.0.0.2:1234"))
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	store := NewMemoryRateLimitStore(time.Minute)
+	rule := RateLimitRule{Rate: 2, Burst: 2}
+	now := time.Now()
+
+	allowed, remaining, _ := store.Take("client", rule, now)
+	assert.True(t, allowed)
+	assert.Equal(t, 1, remaining)
+
+	allowed, _, _ = store.Take("client", rule, now)
+	assert.True(t, allowed)
+
+	allowed, _, reset := store.Take("client", rule, now)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, reset)
+
+	// Half a second refills one token at two tokens per second.
+	allowed, _, _ = store.Take("client", rule, now.Add(500*time.Millisecond))
+	assert.True(t, allowed)
+
+	store.Take("other", rule, now.Add(30*time.Second))
+	assert.Equal(t, 2, store.Len())
+
+	store.Take("other", rule, now.Add(90*time.Second))
+	assert.Equal(t, 1, store.Len())
+}
+
+func TestParseRateLimitRoutes(t *testing.T) {
+	routes, err := ParseRateLimitRoutes("GET /articles/search=2:5, post /articles/create=10")
+	assert.NoError(t, err)
+	assert.Equal(t, RateLimitRule{Rate: 2, Burst: 5}, routes["GET /articles/search"])
+	assert.Equal(t, RateLimitRule{Rate: 10, Burst: 10}, routes["POST /articles/create"])
+
+	_, err = ParseRateLimitRoutes("/articles/search=2")
+	assert.Error(t, err)
+
+	_, err = ParseRateLimitRoutes("GET /articles/search=fast")
+	assert.Error(t, err)
+}
+
+func TestAPIKeyIdentityMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(APIKeyIdentityMiddleware([]string{"first-key-0123456789"}))
+	router.GET("/articles/search", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(APIKeyContextKey))
+	})
+
+	send := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/articles/search", nil)
+		req.Header.Set("X-API-Key", apiKey)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := send("first-key-0123456789")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "first-key-0123456789", resp.Body.String())
+
+	resp = send("wrong-key")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Body.String())
+}
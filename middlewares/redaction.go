@@ -0,0 +1,212 @@
+package middlewares
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const redactedValue = "******"
+
+type RedactionMode string
+
+const (
+	// RedactFull replaces the whole value with "******".
+	RedactFull RedactionMode = "full"
+	// RedactPartial keeps a short suffix (or the email domain) visible so
+	// values can still be told apart in the logs.
+	RedactPartial RedactionMode = "partial"
+)
+
+// RedactionPatterns are the built-in patterns that can be enabled by name.
+var RedactionPatterns = map[string]*regexp.Regexp{
+	"email": regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	"card":  regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+}
+
+type RedactionConfig struct {
+	// Fields are body keys, matched at any depth ignoring case, underscores
+	// and dashes, so "api_key" also matches "apiKey".
+	Fields []string
+	// Headers are request and response header names.
+	Headers []string
+	// Patterns are matched against every string value in the body.
+	Patterns []*regexp.Regexp
+	Mode     RedactionMode
+}
+
+func DefaultRedactionConfig() RedactionConfig {
+	return RedactionConfig{
+		Fields:   []string{"password", "token", "secret", "id_token", "access_token", "refresh_token", "api_key"},
+		Headers:  []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "X-API-Key"},
+		Patterns: []*regexp.Regexp{RedactionPatterns["email"], RedactionPatterns["card"]},
+		Mode:     RedactFull,
+	}
+}
+
+type Redactor struct {
+	fields   map[string]bool
+	headers  map[string]bool
+	patterns []*regexp.Regexp
+	mode     RedactionMode
+	// jsonPair and formPair find "field":"value" and field=value pairs in
+	// bodies that could not be decoded.
+	jsonPair *regexp.Regexp
+	formPair *regexp.Regexp
+}
+
+// fieldSeparators are ignored when field names are compared.
+var fieldSeparators = strings.NewReplacer("_", "", "-", "")
+
+func normalizeField(name string) string {
+	return strings.ToLower(fieldSeparators.Replace(name))
+}
+
+func NewRedactor(config RedactionConfig) *Redactor {
+	r := &Redactor{
+		fields:   map[string]bool{},
+		headers:  map[string]bool{},
+		patterns: config.Patterns,
+		mode:     config.Mode,
+	}
+	names := make([]string, 0, len(config.Fields))
+	for _, field := range config.Fields {
+		r.fields[normalizeField(field)] = true
+		names = append(names, fieldPattern(field))
+	}
+	if len(names) > 0 {
+		alternatives := strings.Join(names, "|")
+		// A value may be cut off by truncation, so the closing quote is
+		// optional.
+		r.jsonPair = regexp.MustCompile(`(?i)("(?:` + alternatives + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^\s,}\]]+)`)
+		r.formPair = regexp.MustCompile(`(?i)((?:^|[&;\s])(?:` + alternatives + `)=)([^&;\s]*)`)
+	}
+	for _, header := range config.Headers {
+		r.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	return r
+}
+
+// Redact returns a copy of a decoded JSON value with sensitive fields masked
+// in nested objects and arrays, and pattern matches masked in strings.
+func (r *Redactor) Redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if r.fields[normalizeField(key)] {
+				redacted[key] = r.mask(item)
+			} else {
+				redacted[key] = r.Redact(item)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = r.Redact(item)
+		}
+		return redacted
+	case string:
+		return r.redactPatterns(v)
+	case rawBody:
+		text := r.redactPatterns(r.redactPairs(v.text))
+		if v.truncated {
+			text += truncatedMarker
+		}
+		return text
+	default:
+		return v
+	}
+}
+
+// fieldPattern matches a field name with or without its separators, the raw
+// text counterpart of normalizeField.
+func fieldPattern(field string) string {
+	var parts []string
+	for _, char := range fieldSeparators.Replace(field) {
+		parts = append(parts, regexp.QuoteMeta(string(char)))
+	}
+	return strings.Join(parts, "[_-]?")
+}
+
+// redactPairs masks the values of sensitive fields written as JSON members or
+// form pairs in text that could not be decoded.
+func (r *Redactor) redactPairs(text string) string {
+	if r.jsonPair == nil {
+		return text
+	}
+	text = maskMatches(r.jsonPair, text, func(value string) string {
+		quoted, found := strings.CutPrefix(value, `"`)
+		if !found {
+			return r.maskString(value)
+		}
+		inner, closed := strings.CutSuffix(quoted, `"`)
+		masked := `"` + r.maskString(inner)
+		if closed && quoted != "" {
+			masked += `"`
+		}
+		return masked
+	})
+	return maskMatches(r.formPair, text, r.maskString)
+}
+
+// maskMatches replaces the second group of every match of pattern with mask
+// of it, keeping the first.
+func maskMatches(pattern *regexp.Regexp, text string, mask func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, match := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(text[last:match[3]])
+		b.WriteString(mask(text[match[4]:match[5]]))
+		last = match[5]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// RedactHeaders returns a copy of headers with sensitive headers masked.
+func (r *Redactor) RedactHeaders(headers http.Header) http.Header {
+	redacted := make(http.Header, len(headers))
+	for key, values := range headers {
+		if !r.headers[http.CanonicalHeaderKey(key)] {
+			redacted[key] = values
+			continue
+		}
+		masked := make([]string, len(values))
+		for i, value := range values {
+			masked[i] = r.maskString(value)
+		}
+		redacted[key] = masked
+	}
+	return redacted
+}
+
+func (r *Redactor) mask(value interface{}) interface{} {
+	if s, ok := value.(string); ok {
+		return r.maskString(s)
+	}
+	return redactedValue
+}
+
+func (r *Redactor) maskString(value string) string {
+	if r.mode == RedactPartial && len(value) > 8 {
+		return redactedValue + value[len(value)-4:]
+	}
+	return redactedValue
+}
+
+func (r *Redactor) redactPatterns(value string) string {
+	for _, pattern := range r.patterns {
+		value = pattern.ReplaceAllStringFunc(value, func(match string) string {
+			if r.mode != RedactPartial {
+				return redactedValue
+			}
+			if at := strings.LastIndex(match, "@"); at > 0 {
+				return match[:1] + redactedValue + match[at:]
+			}
+			return r.maskString(match)
+		})
+	}
+	return value
+}
@@ -0,0 +1,95 @@
+package middlewares
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactorNestedFields(t *testing.T) {
+	redactor := NewRedactor(DefaultRedactionConfig())
+
+	body := map[string]interface{}{
+		"username": "admin",
+		"user": map[string]interface{}{
+			"Password": "12345",
+			"profile":  map[string]interface{}{"token": "abc"},
+		},
+		"sessions": []interface{}{
+			map[string]interface{}{"id_token": "xyz", "device": "phone"},
+		},
+	}
+
+	expected := map[string]interface{}{
+		"username": "admin",
+		"user": map[string]interface{}{
+			"Password": "******",
+			"profile":  map[string]interface{}{"token": "******"},
+		},
+		"sessions": []interface{}{
+			map[string]interface{}{"id_token": "******", "device": "phone"},
+		},
+	}
+	assert.Equal(t, expected, redactor.Redact(body))
+
+	// The original body is left untouched.
+	assert.Equal(t, "12345", body["user"].(map[string]interface{})["Password"])
+}
+
+func TestRedactorPatterns(t *testing.T) {
+	redactor := NewRedactor(DefaultRedactionConfig())
+	assert.Equal(t, "contact ****** or pay with ******",
+		redactor.Redact("contact jane@example.com or pay with 4111 1111 1111 1111"))
+
+	config := DefaultRedactionConfig()
+	config.Mode = RedactPartial
+	redactor = NewRedactor(config)
+	assert.Equal(t, "contact j******@example.com or pay with ******1111",
+		redactor.Redact("contact jane@example.com or pay with 4111111111111111"))
+	assert.Equal(t, map[string]interface{}{"password": "******"},
+		redactor.Redact(map[string]interface{}{"password": "short"}))
+	assert.Equal(t, map[string]interface{}{"secret": "******cdef"},
+		redactor.Redact(map[string]interface{}{"secret": "0123456789abcdef"}))
+
+	config = RedactionConfig{Patterns: []*regexp.Regexp{regexp.MustCompile(`SSN-\d+`)}}
+	assert.Equal(t, "id ******", NewRedactor(config).Redact("id SSN-1234"))
+}
+
+func TestRedactorFieldSpellings(t *testing.T) {
+	redactor := NewRedactor(DefaultRedactionConfig())
+	assert.Equal(t,
+		map[string]interface{}{"apiKey": "******", "API-KEY": "******", "idToken": "******", "title": "Go"},
+		redactor.Redact(map[string]interface{}{"apiKey": "k1", "API-KEY": "k2", "idToken": "t", "title": "Go"}))
+}
+
+func TestRedactorRawBodies(t *testing.T) {
+	redactor := NewRedactor(DefaultRedactionConfig())
+
+	assert.Equal(t, `{"user":"jane","apiKey":"******","password": "******","token":******,"title":"password=x"}`,
+		redactor.Redact(rawBody{text: `{"user":"jane","apiKey":"abc123","password": "hunter2","token":42,"title":"password=x"}`}))
+	assert.Equal(t, `{"title":"A","password":"******`+truncatedMarker,
+		redactor.Redact(rawBody{text: `{"title":"A","password":"hunt`, truncated: true}))
+	assert.Equal(t, `user=jane&password=******&api_key=******`,
+		redactor.Redact(rawBody{text: `user=jane&password=hunter2&api_key=abc123`}))
+	assert.Equal(t, "mail ******", redactor.Redact(rawBody{text: "mail jane@example.com"}))
+
+	// Plain strings inside decoded bodies are only matched against patterns.
+	assert.Equal(t, "password=x", redactor.Redact("password=x"))
+}
+
+func TestRedactorHeaders(t *testing.T) {
+	redactor := NewRedactor(DefaultRedactionConfig())
+
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer secret-token")
+	headers.Set("Cookie", "session=abc")
+	headers.Set("Content-Type", "application/json")
+
+	redacted := redactor.RedactHeaders(headers)
+	assert.Equal(t, "******", redacted.Get("Authorization"))
+	assert.Equal(t, "******", redacted.Get("Cookie"))
+	assert.Equal(t, "application/json", redacted.Get("Content-Type"))
+	assert.Equal(t, "Bearer secret-token", headers.Get("Authorization"))
+}
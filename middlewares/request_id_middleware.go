@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"github.com/brothergiez/restful-api/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDMiddleware reuses the client's X-Request-ID when it is valid and
+// generates one otherwise. The ID is stored in the gin context and the request
+// context, and echoed back in the response header.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.Generate()
+		}
+
+		c.Set(requestid.GinKey, id)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Header(requestid.Header, id)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brothergiez/restful-api/requestid"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"gin":     c.GetString(requestid.GinKey),
+			"context": requestid.FromContext(c.Request.Context()),
+		})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set(requestid.Header, "client-id-1")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, "client-id-1", resp.Header().Get(requestid.Header))
+	assert.JSONEq(t, `{"gin":"client-id-1","context":"client-id-1"}`, resp.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set(requestid.Header, "not valid")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	generated := resp.Header().Get(requestid.Header)
+	assert.Len(t, generated, 32)
+	assert.NotEqual(t, "not valid", generated)
+}
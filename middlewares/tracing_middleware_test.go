@@ -0,0 +1,84 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brothergiez/restful-api/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func setupTestTracing(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return recorder
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	recorder := setupTestTracing(t)
+
+	var logBuffer bytes.Buffer
+	handler, err := logger.NewHandler(&logBuffer, "json", slog.LevelInfo)
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(TracingMiddleware())
+	router.Use(LoggingMiddleware(slog.New(handler)))
+	router.GET("/articles/search", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/articles/search?keyword=go", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "GET /articles/search", spans[0].Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+
+	var logEntry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(logBuffer.Bytes(), &logEntry))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", logEntry["trace_id"])
+	assert.Equal(t, spans[0].SpanContext().SpanID().String(), logEntry["span_id"])
+}
+
+func TestTracingMiddlewareNewTrace(t *testing.T) {
+	recorder := setupTestTracing(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(TracingMiddleware())
+	router.GET("/fail", func(c *gin.Context) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent().IsValid())
+	assert.True(t, spans[0].SpanContext().IsValid())
+	assert.Equal(t, "Error", spans[0].Status().Code.String())
+}
@@ -1,7 +1,22 @@
 package models
 
+import "strings"
+
 type Article struct {
-	ID      int    `json:"id"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	ID      int    `json:"id" xml:"id"`
+	Title   string `json:"title" xml:"title"`
+	Content string `json:"content" xml:"content"`
+	// Author and Tags are optional and left out of the JSON when empty.
+	Author string   `json:"author,omitempty" xml:"author,omitempty"`
+	Tags   []string `json:"tags,omitempty" xml:"tags>tag,omitempty"`
+}
+
+// HasTag reports whether the article carries tag, ignoring case.
+func (a Article) HasTag(tag string) bool {
+	for _, t := range a.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,331 @@
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/internal/randomid"
+)
+
+// sendBuffer is the number of messages a client may fall behind by before
+// it is disconnected.
+const sendBuffer = 64
+
+type Config struct {
+	// PingInterval is how often clients are pinged, and how often stale
+	// sessions are looked for.
+	PingInterval time.Duration
+	// StaleAfter ends a session that has not answered a ping or sent anything
+	// for this long. It must be longer than PingInterval.
+	StaleAfter time.Duration
+	// MaxSessions limits the sessions on one article.
+	MaxSessions int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		PingInterval: 20 * time.Second,
+		StaleAfter:   time.Minute,
+		MaxSessions:  50,
+	}
+}
+
+type Option func(*Hub)
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(h *Hub) {
+		h.logger = logger
+	}
+}
+
+// Client is the hub side of a session. The transport sends what arrives on
+// Messages to the connection and hands incoming frames to Receive.
+type Client struct {
+	hub       *Hub
+	articleID int
+	session   Session
+	lastSeen  time.Time
+	send      chan Message
+	reason    string
+}
+
+// Messages is closed when the session ends; Reason then says why.
+func (c *Client) Messages() <-chan Message {
+	return c.send
+}
+
+// Reason is one of the Reason* constants once Messages is closed.
+func (c *Client) Reason() string {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	return c.reason
+}
+
+// Touch records a sign of life, such as a pong.
+func (c *Client) Touch() {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	c.lastSeen = c.hub.now()
+}
+
+// Receive handles a message from the client. Invalid messages are answered
+// with an error message and otherwise ignored.
+func (c *Client) Receive(data []byte) {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.rooms[c.articleID][c.session.ID] != c {
+		return
+	}
+	c.lastSeen = h.now()
+
+	var msg ClientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		h.sendTo(c, Message{Type: MessageError, Error: "message must be a JSON object"})
+		return
+	}
+
+	switch msg.Type {
+	case MessagePing:
+		h.sendTo(c, Message{Type: MessagePong})
+	case MessageState:
+		if msg.State != StateViewing && msg.State != StateEditing {
+			h.sendTo(c, Message{Type: MessageError, Error: "state must be viewing or editing"})
+			return
+		}
+		c.session.State = msg.State
+		h.broadcast(c.articleID, Message{Type: MessageState, Session: c.snapshot()}, nil)
+	case MessageCursor:
+		if msg.Cursor != nil && !msg.Cursor.valid() {
+			h.sendTo(c, Message{Type: MessageError, Error: "cursor needs a field of title or content and a non-negative offset and length"})
+			return
+		}
+		c.session.Cursor = msg.Cursor
+		// The sender knows where its own cursor is.
+		h.broadcast(c.articleID, Message{Type: MessageCursor, Session: c.snapshot()}, c)
+	default:
+		h.sendTo(c, Message{Type: MessageError, Error: "unknown message type " + msg.Type})
+	}
+}
+
+// Leave ends the session.
+func (c *Client) Leave() {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	c.hub.remove(ReasonLeft, c)
+}
+
+func (c *Client) snapshot() *Session {
+	session := c.session
+	if session.Cursor != nil {
+		cursor := *session.Cursor
+		session.Cursor = &cursor
+	}
+	return &session
+}
+
+// Hub tracks who is on each article and relays their state and cursor
+// changes to the others, along with changes to the article itself.
+type Hub struct {
+	config Config
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu     sync.Mutex
+	rooms  map[int]map[string]*Client
+	closed bool
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewHub starts a hub and its stale session sweeper.
+func NewHub(config Config, opts ...Option) *Hub {
+	h := &Hub{
+		config: config,
+		logger: slog.Default(),
+		now:    time.Now,
+		rooms:  map[int]map[string]*Client{},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	go func() {
+		defer close(h.done)
+		ticker := time.NewTicker(config.PingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.stop:
+				return
+			case <-ticker.C:
+				h.sweep()
+			}
+		}
+	}()
+	return h
+}
+
+// PingInterval is how often transports should ping their client.
+func (h *Hub) PingInterval() time.Duration {
+	return h.config.PingInterval
+}
+
+// Join adds a session for user to an article. Its first message is a welcome
+// listing everyone present; the others are told it joined.
+func (h *Hub) Join(articleID int, user string) (*Client, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+	room := h.rooms[articleID]
+	if len(room) >= h.config.MaxSessions {
+		return nil, ErrRoomFull
+	}
+	if room == nil {
+		room = map[string]*Client{}
+		h.rooms[articleID] = room
+	}
+
+	now := h.now()
+	c := &Client{
+		hub:       h,
+		articleID: articleID,
+		session:   Session{ID: randomid.Hex(8), User: user, State: StateViewing, JoinedAt: now},
+		lastSeen:  now,
+		send:      make(chan Message, sendBuffer),
+	}
+	h.broadcast(articleID, Message{Type: MessageJoined, Session: c.snapshot()}, nil)
+	room[c.session.ID] = c
+
+	sessions := make([]Session, 0, len(room))
+	for _, other := range room {
+		sessions = append(sessions, *other.snapshot())
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].JoinedAt.Equal(sessions[j].JoinedAt) {
+			return sessions[i].JoinedAt.Before(sessions[j].JoinedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	c.send <- Message{Type: MessageWelcome, SessionID: c.session.ID, Sessions: sessions}
+	return c, nil
+}
+
+// HandleEvent tells the sessions of an article that it was updated or
+// deleted. Deleting an article also ends its sessions. It is meant to be an
+// asynchronous bus subscriber.
+func (h *Hub) HandleEvent(_ context.Context, event events.Event) {
+	if event.Type() != events.TypeArticleUpdated && event.Type() != events.TypeArticleDeleted {
+		return
+	}
+	article := event.Subject()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.broadcast(article.ID, Message{Type: event.Type(), Article: &article}, nil)
+	if event.Type() == events.TypeArticleDeleted {
+		var ended []*Client
+		for _, c := range h.rooms[article.ID] {
+			ended = append(ended, c)
+		}
+		h.remove(ReasonDeleted, ended...)
+	}
+}
+
+// Close ends every session and stops the sweeper. Later joins fail with
+// ErrClosed.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+	h.closed = true
+	for _, room := range h.rooms {
+		for _, c := range room {
+			c.reason = ReasonShutdown
+			close(c.send)
+		}
+	}
+	h.rooms = map[int]map[string]*Client{}
+	h.mu.Unlock()
+
+	close(h.stop)
+	<-h.done
+}
+
+// sweep ends the sessions that have been silent for longer than StaleAfter.
+func (h *Hub) sweep() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	deadline := h.now().Add(-h.config.StaleAfter)
+	var stale []*Client
+	for _, room := range h.rooms {
+		for _, c := range room {
+			if c.lastSeen.Before(deadline) {
+				stale = append(stale, c)
+			}
+		}
+	}
+	if len(stale) > 0 {
+		h.logger.Info("Ending stale presence sessions", "sessions", len(stale))
+	}
+	h.remove(ReasonStale, stale...)
+}
+
+// sendTo queues msg for c, disconnecting it when its queue is full. It must
+// be called with h.mu held.
+func (h *Hub) sendTo(c *Client, msg Message) {
+	select {
+	case c.send <- msg:
+	default:
+		h.remove(ReasonSlow, c)
+	}
+}
+
+// broadcast queues msg for every session of an article except skip. It must
+// be called with h.mu held.
+func (h *Hub) broadcast(articleID int, msg Message, skip *Client) {
+	var slow []*Client
+	for _, c := range h.rooms[articleID] {
+		if c == skip {
+			continue
+		}
+		select {
+		case c.send <- msg:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	h.remove(ReasonSlow, slow...)
+}
+
+// remove ends sessions and tells the rest of their article. It must be
+// called with h.mu held.
+func (h *Hub) remove(reason string, clients ...*Client) {
+	for _, c := range clients {
+		room := h.rooms[c.articleID]
+		if room[c.session.ID] != c {
+			continue
+		}
+		delete(room, c.session.ID)
+		if len(room) == 0 {
+			delete(h.rooms, c.articleID)
+		}
+		c.reason = reason
+		close(c.send)
+		if reason == ReasonSlow {
+			h.logger.Warn("Presence client too slow, disconnected", "article", c.articleID, "session", c.session.ID)
+		}
+		h.broadcast(c.articleID, Message{Type: MessageLeft, Session: c.snapshot()}, nil)
+	}
+}
@@ -0,0 +1,171 @@
+package presence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHub(t *testing.T, config Config) *Hub {
+	h := NewHub(config)
+	t.Cleanup(h.Close)
+	return h
+}
+
+func testConfig() Config {
+	config := DefaultConfig()
+	// The sweeper is driven by the tests.
+	config.PingInterval = time.Hour
+	return config
+}
+
+func next(t *testing.T, c *Client) Message {
+	select {
+	case msg := <-c.Messages():
+		return msg
+	default:
+		t.Fatal("no message queued")
+		return Message{}
+	}
+}
+
+func TestHubJoinAndLeave(t *testing.T) {
+	h := newTestHub(t, testConfig())
+
+	alice, err := h.Join(1, "alice")
+	assert.NoError(t, err)
+	welcome := next(t, alice)
+	assert.Equal(t, MessageWelcome, welcome.Type)
+	assert.Equal(t, alice.session.ID, welcome.SessionID)
+	assert.Len(t, welcome.Sessions, 1)
+
+	bob, _ := h.Join(1, "bob")
+	joined := next(t, alice)
+	assert.Equal(t, MessageJoined, joined.Type)
+	assert.Equal(t, "bob", joined.Session.User)
+	assert.Equal(t, StateViewing, joined.Session.State)
+	welcome = next(t, bob)
+	assert.Equal(t, []string{"alice", "bob"}, []string{welcome.Sessions[0].User, welcome.Sessions[1].User})
+
+	// Other articles are separate.
+	carol, _ := h.Join(2, "carol")
+	assert.Len(t, next(t, carol).Sessions, 1)
+	assert.Empty(t, alice.Messages())
+
+	bob.Leave()
+	bob.Leave()
+	left := next(t, alice)
+	assert.Equal(t, MessageLeft, left.Type)
+	assert.Equal(t, bob.session.ID, left.Session.ID)
+	_, open := <-bob.Messages()
+	assert.False(t, open)
+	assert.Equal(t, ReasonLeft, bob.Reason())
+}
+
+func TestHubRelaysStateAndCursor(t *testing.T) {
+	h := newTestHub(t, testConfig())
+	alice, _ := h.Join(1, "alice")
+	bob, _ := h.Join(1, "bob")
+	next(t, alice)
+	next(t, alice)
+	next(t, bob)
+
+	alice.Receive([]byte(`{"type":"state","state":"editing"}`))
+	assert.Equal(t, Message{Type: MessageState, Session: alice.snapshot()}, next(t, bob))
+	assert.Equal(t, MessageState, next(t, alice).Type)
+
+	alice.Receive([]byte(`{"type":"cursor","cursor":{"field":"content","offset":12,"length":3}}`))
+	msg := next(t, bob)
+	assert.Equal(t, MessageCursor, msg.Type)
+	assert.Equal(t, &Cursor{Field: "content", Offset: 12, Length: 3}, msg.Session.Cursor)
+	assert.Equal(t, StateEditing, msg.Session.State)
+	assert.Empty(t, alice.Messages())
+
+	alice.Receive([]byte(`{"type":"cursor","cursor":null}`))
+	assert.Nil(t, next(t, bob).Session.Cursor)
+
+	alice.Receive([]byte(`{"type":"ping"}`))
+	assert.Equal(t, MessagePong, next(t, alice).Type)
+
+	for _, data := range []string{
+		`not json`,
+		`{"type":"state","state":"sleeping"}`,
+		`{"type":"cursor","cursor":{"field":"author","offset":1}}`,
+		`{"type":"cursor","cursor":{"field":"title","offset":-1}}`,
+		`{"type":"wave"}`,
+	} {
+		alice.Receive([]byte(data))
+		assert.Equal(t, MessageError, next(t, alice).Type, data)
+	}
+	assert.Empty(t, bob.Messages())
+}
+
+func TestHubArticleEvents(t *testing.T) {
+	h := newTestHub(t, testConfig())
+	alice, _ := h.Join(1, "alice")
+	other, _ := h.Join(2, "bob")
+	next(t, alice)
+	next(t, other)
+
+	article := models.Article{ID: 1, Title: "New", Content: "Body"}
+	h.HandleEvent(context.Background(), events.ArticleCreated{Article: article})
+	assert.Empty(t, alice.Messages())
+
+	h.HandleEvent(context.Background(), events.ArticleUpdated{Article: article})
+	assert.Equal(t, Message{Type: events.TypeArticleUpdated, Article: &article}, next(t, alice))
+
+	h.HandleEvent(context.Background(), events.ArticleDeleted{Article: article})
+	assert.Equal(t, events.TypeArticleDeleted, next(t, alice).Type)
+	_, open := <-alice.Messages()
+	assert.False(t, open)
+	assert.Equal(t, ReasonDeleted, alice.Reason())
+	assert.Empty(t, other.Messages())
+}
+
+func TestHubEndsStaleAndSlowSessions(t *testing.T) {
+	h := newTestHub(t, testConfig())
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	alice, _ := h.Join(1, "alice")
+	bob, _ := h.Join(1, "bob")
+	now = now.Add(45 * time.Second)
+	alice.Touch()
+	now = now.Add(30 * time.Second)
+	h.sweep()
+
+	assert.Equal(t, ReasonStale, bob.Reason())
+	assert.Equal(t, "", alice.Reason())
+
+	// Alice never reads, so her queue fills up.
+	for i := 0; i < sendBuffer; i++ {
+		h.HandleEvent(context.Background(), events.ArticleUpdated{Article: models.Article{ID: 1}})
+	}
+	assert.Equal(t, ReasonSlow, alice.Reason())
+}
+
+func TestHubLimitsAndClose(t *testing.T) {
+	config := testConfig()
+	config.MaxSessions = 1
+	h := NewHub(config)
+
+	alice, err := h.Join(1, "alice")
+	assert.NoError(t, err)
+	_, err = h.Join(1, "bob")
+	assert.ErrorIs(t, err, ErrRoomFull)
+
+	h.Close()
+	h.Close()
+	next(t, alice)
+	_, open := <-alice.Messages()
+	assert.False(t, open)
+	assert.Equal(t, ReasonShutdown, alice.Reason())
+	alice.Receive([]byte(`{"type":"ping"}`))
+	alice.Leave()
+	_, err = h.Join(2, "bob")
+	assert.ErrorIs(t, err, ErrClosed)
+}
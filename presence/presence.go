@@ -0,0 +1,86 @@
+package presence
+
+import (
+	"errors"
+	"time"
+
+	"github.com/brothergiez/restful-api/models"
+)
+
+// Session states.
+const (
+	StateViewing = "viewing"
+	StateEditing = "editing"
+)
+
+// Message types. Clients send state, cursor and ping; everything else comes
+// from the server. Article changes use the event type, e.g. article.updated.
+const (
+	MessageWelcome = "welcome"
+	MessageJoined  = "joined"
+	MessageLeft    = "left"
+	MessageState   = "state"
+	MessageCursor  = "cursor"
+	MessagePing    = "ping"
+	MessagePong    = "pong"
+	MessageError   = "error"
+)
+
+// Reasons a session ends, as returned by Client.Reason.
+const (
+	ReasonLeft     = "left"
+	ReasonStale    = "no heartbeat"
+	ReasonSlow     = "too slow to receive updates"
+	ReasonDeleted  = "article deleted"
+	ReasonShutdown = "server shutting down"
+)
+
+var (
+	// ErrRoomFull is returned by Join when an article has MaxSessions
+	// sessions already.
+	ErrRoomFull = errors.New("too many sessions on this article")
+	// ErrClosed is returned by Join once the hub is closed.
+	ErrClosed = errors.New("presence hub closed")
+)
+
+// Cursor is a caret or selection in one field of the article. Offset and
+// Length count characters.
+type Cursor struct {
+	Field  string `json:"field"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length,omitempty"`
+}
+
+func (c Cursor) valid() bool {
+	return (c.Field == "title" || c.Field == "content") && c.Offset >= 0 && c.Length >= 0
+}
+
+// Session is one connected client of an article.
+type Session struct {
+	ID       string    `json:"id"`
+	User     string    `json:"user"`
+	State    string    `json:"state"`
+	Cursor   *Cursor   `json:"cursor,omitempty"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// Message is sent to clients as a JSON text frame. Welcome carries the
+// client's own SessionID and everyone present in Sessions; joined, left,
+// state and cursor carry the Session that changed.
+type Message struct {
+	Type      string          `json:"type"`
+	SessionID string          `json:"sessionId,omitempty"`
+	Session   *Session        `json:"session,omitempty"`
+	Sessions  []Session       `json:"sessions,omitempty"`
+	Article   *models.Article `json:"article,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// ClientMessage is what clients send: {"type":"state","state":"editing"},
+// {"type":"cursor","cursor":{...}} with a null cursor to clear it, or
+// {"type":"ping"}.
+type ClientMessage struct {
+	Type   string  `json:"type"`
+	State  string  `json:"state,omitempty"`
+	Cursor *Cursor `json:"cursor,omitempty"`
+}
@@ -1,48 +1,216 @@
 package repositories
 
 import (
+	"context"
 	"errors"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/brothergiez/restful-api/events"
 	"github.com/brothergiez/restful-api/models"
+	"github.com/brothergiez/restful-api/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Observer receives the duration and outcome of every repository operation.
+type Observer interface {
+	ObserveOperation(operation string, duration time.Duration, err error)
+}
+
+// Publisher receives the events of successful writes, after the write lock
+// has been released. Calls are made one at a time, in the order the writes
+// were applied, so a publisher must not write to the repository while
+// handling them. *events.Bus implements it.
+type Publisher interface {
+	Publish(ctx context.Context, events ...events.Event)
+}
+
+type Option func(*ArticleRepository)
+
+func WithObserver(observer Observer) Option {
+	return func(r *ArticleRepository) {
+		r.observer = observer
+	}
+}
+
+func WithPublisher(publisher Publisher) Option {
+	return func(r *ArticleRepository) {
+		r.publisher = publisher
+	}
+}
+
+var (
+	// ErrClosed is returned by Ping once the repository has been closed.
+	ErrClosed = errors.New("repository closed")
+	// ErrNotFound is returned when no article has the requested ID.
+	ErrNotFound = errors.New("article not found")
 )
 
 type ArticleRepository struct {
-	articles []models.Article
-	nextID   int
+	mu sync.RWMutex
+	// articles is kept sorted by ID. New articles get nextID, which is always
+	// past the largest ID, so appending preserves the order.
+	articles  []models.Article
+	nextID    int
+	observer  Observer
+	publisher Publisher
+	// publishMu orders the Publish calls; see unlockAndPublish.
+	publishMu sync.Mutex
+	closed    bool
+	// persist is nil unless the repository was opened with
+	// OpenArticleRepository.
+	persist *persistence
 }
 
-func NewArticleRepository() *ArticleRepository {
-	return &ArticleRepository{
+func NewArticleRepository(opts ...Option) *ArticleRepository {
+	r := &ArticleRepository{
 		articles: []models.Article{},
 		nextID:   1,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// instrument starts a span named after method and returns a function that
+// ends it and reports the operation's duration and outcome to the observer.
+func (r *ArticleRepository) instrument(ctx context.Context, operation, method string, attrs ...attribute.KeyValue) func(error) {
+	startTime := time.Now()
+	_, span := tracing.Tracer().Start(ctx, "ArticleRepository."+method,
+		trace.WithAttributes(attribute.String("repository.operation", operation)),
+		trace.WithAttributes(attrs...))
+
+	return func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+		if r.observer != nil {
+			r.observer.ObserveOperation(operation, time.Since(startTime), err)
+		}
+	}
+}
+
+// unlockAndPublish releases the write lock and sends the events of the write.
+// Writers defer it right after taking the lock. The publish lock is taken
+// before the write lock is released, so events are published in the order
+// the writes were applied, while subscribers run without the write lock and
+// may read the repository.
+func (r *ArticleRepository) unlockAndPublish(ctx context.Context, emitted *[]events.Event) {
+	if r.publisher == nil || len(*emitted) == 0 {
+		r.mu.Unlock()
+		return
+	}
+	r.publishMu.Lock()
+	defer r.publishMu.Unlock()
+	r.mu.Unlock()
+	r.publisher.Publish(ctx, *emitted...)
 }
 
-func (r *ArticleRepository) CreateArticle(title, content string) models.Article {
+// CreateArticle stores draft under the next ID. The ID of draft is ignored.
+func (r *ArticleRepository) CreateArticle(ctx context.Context, draft models.Article) (models.Article, error) {
+	done := r.instrument(ctx, "create", "CreateArticle")
+	var emitted []events.Event
+	r.mu.Lock()
+	defer r.unlockAndPublish(ctx, &emitted)
+
 	article := models.Article{
 		ID:      r.nextID,
-		Title:   title,
-		Content: content,
+		Title:   draft.Title,
+		Content: draft.Content,
+		Author:  draft.Author,
+		Tags:    slices.Clone(draft.Tags),
+	}
+	if err := r.log(createOp(article)); err != nil {
+		done(err)
+		return models.Article{}, err
 	}
 	r.articles = append(r.articles, article)
 	r.nextID++
+	emitted = append(emitted, events.ArticleCreated{Article: article, At: time.Now()})
+
+	done(nil)
+	return article, nil
+}
+
+// ArticleChanges is an update of an article. The title and content are
+// replaced; the author and tags only when they are set, so a nil Author or
+// Tags keeps the current one. An empty Tags removes every tag.
+type ArticleChanges struct {
+	Title   string
+	Content string
+	Author  *string
+	Tags    *[]string
+}
+
+func (c ArticleChanges) apply(article models.Article) models.Article {
+	article.Title = c.Title
+	article.Content = c.Content
+	if c.Author != nil {
+		article.Author = *c.Author
+	}
+	if c.Tags != nil {
+		article.Tags = slices.Clone(*c.Tags)
+	}
 	return article
 }
 
-func (r *ArticleRepository) UpdateArticle(id int, title, content string) (models.Article, error) {
+// UpdateArticle applies changes to the article with id.
+func (r *ArticleRepository) UpdateArticle(ctx context.Context, id int, changes ArticleChanges) (models.Article, error) {
+	done := r.instrument(ctx, "update", "UpdateArticle")
+	var emitted []events.Event
+	r.mu.Lock()
+	defer r.unlockAndPublish(ctx, &emitted)
+
 	for i, article := range r.articles {
 		if article.ID == id {
-			r.articles[i].Title = title
-			r.articles[i].Content = content
-			return r.articles[i], nil
+			previous := article
+			article = changes.apply(article)
+			if err := r.log(updateOp(article)); err != nil {
+				done(err)
+				return models.Article{}, err
+			}
+			r.articles[i] = article
+			emitted = append(emitted, events.ArticleUpdated{Article: article, Previous: previous, At: time.Now()})
+			done(nil)
+			return article, nil
+		}
+	}
+
+	done(ErrNotFound)
+	return models.Article{}, ErrNotFound
+}
+
+// GetArticle returns the article with id, or ErrNotFound.
+func (r *ArticleRepository) GetArticle(ctx context.Context, id int) (models.Article, error) {
+	done := r.instrument(ctx, "get", "GetArticle")
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, article := range r.articles {
+		if article.ID == id {
+			done(nil)
+			return article, nil
 		}
 	}
 
-	return models.Article{}, errors.New("article not found")
+	done(ErrNotFound)
+	return models.Article{}, ErrNotFound
 }
 
-func (r *ArticleRepository) SearchArticles(keyword string) []models.Article {
+func (r *ArticleRepository) SearchArticles(ctx context.Context, keyword string) []models.Article {
+	done := r.instrument(ctx, "search", "SearchArticles")
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	keyword = strings.ToLower(keyword)
 	result := []models.Article{}
 	for _, article := range r.articles {
@@ -51,10 +219,16 @@ func (r *ArticleRepository) SearchArticles(keyword string) []models.Article {
 		}
 	}
 
+	done(nil)
 	return result
 }
 
-func (r *ArticleRepository) GetAllArticlesWithPagination(page, limit int) ([]models.Article, int) {
+func (r *ArticleRepository) GetAllArticlesWithPagination(ctx context.Context, page, limit int) ([]models.Article, int) {
+	done := r.instrument(ctx, "get_all", "GetAllArticlesWithPagination")
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	defer done(nil)
+
 	start := (page - 1) * limit
 	end := start + limit
 
@@ -65,5 +239,112 @@ func (r *ArticleRepository) GetAllArticlesWithPagination(page, limit int) ([]mod
 		end = len(r.articles)
 	}
 
-	return r.articles[start:end], len(r.articles)
+	// Copy the page so callers never share the backing array with later writes.
+	return append([]models.Article{}, r.articles[start:end]...), len(r.articles)
+}
+
+// ArticleFilter selects articles by tag and author, ignoring case. Empty
+// fields match every article.
+type ArticleFilter struct {
+	Tag    string
+	Author string
+}
+
+func (f ArticleFilter) Matches(article models.Article) bool {
+	return (f.Tag == "" || article.HasTag(f.Tag)) &&
+		(f.Author == "" || strings.EqualFold(article.Author, f.Author))
+}
+
+// ListArticles returns a page of the articles matching filter, in ID order,
+// and the number of matching articles.
+func (r *ArticleRepository) ListArticles(ctx context.Context, filter ArticleFilter, page, limit int) ([]models.Article, int) {
+	done := r.instrument(ctx, "list", "ListArticles")
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	defer done(nil)
+
+	start := (page - 1) * limit
+	result := []models.Article{}
+	total := 0
+	for _, article := range r.articles {
+		if !filter.Matches(article) {
+			continue
+		}
+		if total >= start && len(result) < limit {
+			result = append(result, article)
+		}
+		total++
+	}
+	return result, total
+}
+
+// CountArticles returns the number of stored articles.
+func (r *ArticleRepository) CountArticles(ctx context.Context) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.articles)
+}
+
+// Ping reports whether the repository can serve requests. The in-memory store
+// has no connection to lose, so it only fails once closed or when ctx is
+// already done.
+func (r *ArticleRepository) Ping(ctx context.Context) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return ErrClosed
+	}
+	if r.persist != nil {
+		if err := r.persist.err(); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// Close marks the repository as closed, after which readiness checks fail. A
+// persistent repository also writes a final snapshot and closes its log,
+// after which writes fail with ErrClosed. It is registered as a server
+// shutdown hook, so it runs once in-flight requests have drained.
+func (r *ArticleRepository) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.mu.Unlock()
+
+	if r.persist == nil {
+		return nil
+	}
+	// Background snapshots take the write lock, so they are stopped before
+	// taking it again for the final one.
+	r.persist.close()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.persist.finish(r)
+}
+
+// upsert stores article under its ID, keeping the slice sorted, and reports
+// whether it was new.
+func (r *ArticleRepository) upsert(article models.Article) bool {
+	i := sort.Search(len(r.articles), func(i int) bool { return r.articles[i].ID >= article.ID })
+	if i < len(r.articles) && r.articles[i].ID == article.ID {
+		r.articles[i] = article
+		return false
+	}
+	r.articles = append(r.articles, models.Article{})
+	copy(r.articles[i+1:], r.articles[i:])
+	r.articles[i] = article
+	r.nextID = max(r.nextID, article.ID+1)
+	return true
+}
+
+// remove deletes the article with id, if any.
+func (r *ArticleRepository) remove(id int) {
+	i := sort.Search(len(r.articles), func(i int) bool { return r.articles[i].ID >= id })
+	if i < len(r.articles) && r.articles[i].ID == id {
+		r.articles = append(r.articles[:i], r.articles[i+1:]...)
+	}
 }
@@ -1,16 +1,21 @@
 package repositories
 
 import (
+	"context"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/models"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestCreateArticle(t *testing.T) {
 	repo := NewArticleRepository()
 
-	article := repo.CreateArticle("Test Title", "Test Content")
+	article, _ := repo.CreateArticle(context.Background(), models.Article{Title: "Test Title", Content: "Test Content"})
 	assert.Equal(t, 1, article.ID)
 	assert.Equal(t, "Test Title", article.Title)
 	assert.Equal(t, "Test Content", article.Content)
@@ -21,60 +26,255 @@ func TestCreateArticle(t *testing.T) {
 
 func TestUpdateArticle(t *testing.T) {
 	repo := NewArticleRepository()
-	article := repo.CreateArticle("Test Title", "Test Content")
+	article, _ := repo.CreateArticle(context.Background(), models.Article{Title: "Test Title", Content: "Test Content"})
 
-	updatedArticle, err := repo.UpdateArticle(article.ID, "Updated Title", "Updated Content")
+	updatedArticle, err := repo.UpdateArticle(context.Background(), article.ID, ArticleChanges{Title: "Updated Title", Content: "Updated Content"})
 	assert.NoError(t, err)
 	assert.Equal(t, "Updated Title", updatedArticle.Title)
 	assert.Equal(t, "Updated Content", updatedArticle.Content)
 
-	_, err = repo.UpdateArticle(999, "New Title", "New Content")
+	_, err = repo.UpdateArticle(context.Background(), 999, ArticleChanges{Title: "New Title", Content: "New Content"})
 	assert.Error(t, err)
 	assert.Equal(t, "article not found", err.Error())
 }
 
+func TestUpdateArticleKeepsAuthorAndTags(t *testing.T) {
+	repo := NewArticleRepository()
+	article, _ := repo.CreateArticle(context.Background(), models.Article{Title: "Go", Content: "One", Author: "gopher", Tags: []string{"go"}})
+
+	updated, err := repo.UpdateArticle(context.Background(), article.ID, ArticleChanges{Title: "Go v2", Content: "Two"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.Article{ID: article.ID, Title: "Go v2", Content: "Two", Author: "gopher", Tags: []string{"go"}}, updated)
+
+	author := ""
+	updated, err = repo.UpdateArticle(context.Background(), article.ID, ArticleChanges{Title: "Go v3", Content: "Three", Author: &author, Tags: &[]string{}})
+	assert.NoError(t, err)
+	assert.Empty(t, updated.Author)
+	assert.Empty(t, updated.Tags)
+}
+
+func TestGetArticle(t *testing.T) {
+	repo := NewArticleRepository()
+	repo.CreateArticle(context.Background(), models.Article{Title: "First", Content: "One"})
+	second, _ := repo.CreateArticle(context.Background(), models.Article{Title: "Second", Content: "Two", Tags: []string{"go"}})
+
+	article, err := repo.GetArticle(context.Background(), second.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, second, article)
+
+	_, err = repo.GetArticle(context.Background(), 999)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
 func TestSearchArticles(t *testing.T) {
 	repo := NewArticleRepository()
-	repo.CreateArticle("First Article", "Content of the first article")
-	repo.CreateArticle("Second Article", "Content of the second article")
-	repo.CreateArticle("Another Post", "Completely unrelated content")
+	repo.CreateArticle(context.Background(), models.Article{Title: "First Article", Content: "Content of the first article"})
+	repo.CreateArticle(context.Background(), models.Article{Title: "Second Article", Content: "Content of the second article"})
+	repo.CreateArticle(context.Background(), models.Article{Title: "Another Post", Content: "Completely unrelated content"})
 
-	results := repo.SearchArticles("article")
+	results := repo.SearchArticles(context.Background(), "article")
 	assert.Len(t, results, 2)
 	assert.Equal(t, "First Article", results[0].Title)
 	assert.Equal(t, "Second Article", results[1].Title)
 
-	results = repo.SearchArticles("unrelated")
+	results = repo.SearchArticles(context.Background(), "unrelated")
 	assert.Len(t, results, 1)
 	assert.Equal(t, "Another Post", results[0].Title)
 
-	results = repo.SearchArticles("nonexistent")
+	results = repo.SearchArticles(context.Background(), "nonexistent")
 	assert.Len(t, results, 0)
 }
 
 func TestGetAllArticlesWithPagination(t *testing.T) {
 	repo := NewArticleRepository()
 	for i := 1; i <= 15; i++ {
-		repo.CreateArticle("Title "+strconv.Itoa(i), "Content "+strconv.Itoa(i))
+		repo.CreateArticle(context.Background(), models.Article{Title: "Title " + strconv.Itoa(i), Content: "Content " + strconv.Itoa(i)})
 	}
 
-	results, total := repo.GetAllArticlesWithPagination(1, 5)
+	results, total := repo.GetAllArticlesWithPagination(context.Background(), 1, 5)
 	assert.Len(t, results, 5)
 	assert.Equal(t, 15, total)
 	assert.Equal(t, "Title 1", results[0].Title)
 	assert.Equal(t, "Title 5", results[4].Title)
 
-	results, _ = repo.GetAllArticlesWithPagination(3, 5)
+	results, _ = repo.GetAllArticlesWithPagination(context.Background(), 3, 5)
 	assert.Len(t, results, 5)
 	assert.Equal(t, "Title 11", results[0].Title)
 	assert.Equal(t, "Title 15", results[4].Title)
 
-	results, total = repo.GetAllArticlesWithPagination(4, 5)
+	results, total = repo.GetAllArticlesWithPagination(context.Background(), 4, 5)
 	assert.Len(t, results, 0)
 	assert.Equal(t, 15, total)
 
-	results, _ = repo.GetAllArticlesWithPagination(2, 10)
+	results, _ = repo.GetAllArticlesWithPagination(context.Background(), 2, 10)
 	assert.Len(t, results, 5)
 	assert.Equal(t, "Title 11", results[0].Title)
 	assert.Equal(t, "Title 15", results[4].Title)
 }
+
+func TestListArticles(t *testing.T) {
+	repo := NewArticleRepository()
+	for i := 1; i <= 6; i++ {
+		draft := models.Article{Title: "Title " + strconv.Itoa(i), Author: "alice"}
+		if i%2 == 0 {
+			draft.Author = "Bob"
+			draft.Tags = []string{"Go"}
+		}
+		repo.CreateArticle(context.Background(), draft)
+	}
+
+	results, total := repo.ListArticles(context.Background(), ArticleFilter{Tag: "go"}, 1, 2)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []int{2, 4}, []int{results[0].ID, results[1].ID})
+
+	results, total = repo.ListArticles(context.Background(), ArticleFilter{Tag: "go", Author: "bob"}, 2, 2)
+	assert.Equal(t, 3, total)
+	assert.Len(t, results, 1)
+	assert.Equal(t, 6, results[0].ID)
+
+	results, total = repo.ListArticles(context.Background(), ArticleFilter{Author: "carol"}, 1, 10)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, results)
+
+	_, total = repo.ListArticles(context.Background(), ArticleFilter{}, 1, 10)
+	assert.Equal(t, 6, total)
+}
+
+type operationRecord struct {
+	operation string
+	err       error
+}
+
+type recordingObserver struct {
+	records []operationRecord
+}
+
+func (o *recordingObserver) ObserveOperation(operation string, duration time.Duration, err error) {
+	o.records = append(o.records, operationRecord{operation: operation, err: err})
+}
+
+func TestRepositoryObserver(t *testing.T) {
+	observer := &recordingObserver{}
+	repo := NewArticleRepository(WithObserver(observer))
+
+	article, _ := repo.CreateArticle(context.Background(), models.Article{Title: "Test Title", Content: "Test Content"})
+	_, _ = repo.UpdateArticle(context.Background(), article.ID, ArticleChanges{Title: "Updated Title", Content: "Updated Content"})
+	_, _ = repo.UpdateArticle(context.Background(), 999, ArticleChanges{Title: "Updated Title", Content: "Updated Content"})
+	repo.SearchArticles(context.Background(), "title")
+	repo.GetAllArticlesWithPagination(context.Background(), 1, 10)
+
+	assert.Len(t, observer.records, 5)
+	assert.Equal(t, "create", observer.records[0].operation)
+	assert.Equal(t, "update", observer.records[1].operation)
+	assert.NoError(t, observer.records[1].err)
+	assert.Error(t, observer.records[2].err)
+	assert.Equal(t, "search", observer.records[3].operation)
+	assert.Equal(t, "get_all", observer.records[4].operation)
+
+	assert.Equal(t, 1, repo.CountArticles(context.Background()))
+}
+
+func TestPingAndClose(t *testing.T) {
+	repo := NewArticleRepository()
+	assert.NoError(t, repo.Ping(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, repo.Ping(ctx), context.Canceled)
+
+	assert.NoError(t, repo.Close(context.Background()))
+	assert.ErrorIs(t, repo.Ping(context.Background()), ErrClosed)
+}
+
+func TestRepositoryPublishesInWriteOrder(t *testing.T) {
+	bus := events.NewBus()
+	var mu sync.Mutex
+	var published []events.Event
+	bus.Subscribe("test", func(ctx context.Context, event events.Event) {
+		// Give a racing writer time to publish first.
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		published = append(published, event)
+	})
+	repo := NewArticleRepository(WithPublisher(bus))
+	ctx := context.Background()
+	article, _ := repo.CreateArticle(ctx, models.Article{Title: "Shared", Content: "0"})
+	published = nil
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			repo.UpdateArticle(ctx, article.ID, ArticleChanges{Title: "Shared", Content: strconv.Itoa(i)})
+		}()
+		go func() {
+			defer wg.Done()
+			repo.CreateArticle(ctx, models.Article{Title: "New", Content: strconv.Itoa(i)})
+		}()
+	}
+	wg.Wait()
+
+	// Every update follows the one it replaced, and created IDs ascend.
+	current, lastID := article, article.ID
+	for _, event := range published {
+		switch e := event.(type) {
+		case events.ArticleUpdated:
+			assert.Equal(t, current, e.Previous)
+			current = e.Article
+		case events.ArticleCreated:
+			assert.Greater(t, e.Article.ID, lastID)
+			lastID = e.Article.ID
+		}
+	}
+	assert.Len(t, published, 100)
+	stored, _ := repo.GetArticle(ctx, article.ID)
+	assert.Equal(t, stored, current)
+}
+
+func TestRepositoryPublishesEvents(t *testing.T) {
+	bus := events.NewBus()
+	var published []events.Event
+	repo := NewArticleRepository(WithPublisher(bus))
+	bus.Subscribe("test", func(ctx context.Context, event events.Event) {
+		// Subscribers run after the write lock is released.
+		repo.CountArticles(ctx)
+		published = append(published, event)
+	})
+	ctx := context.Background()
+
+	article, _ := repo.CreateArticle(ctx, models.Article{Title: "First", Content: "One"})
+	updated, _ := repo.UpdateArticle(ctx, article.ID, ArticleChanges{Title: "First v2", Content: "One again"})
+	repo.UpdateArticle(ctx, 99, ArticleChanges{Title: "Missing", Content: "Missing"})
+	repo.BulkWrite(ctx, []BulkOperation{
+		{Action: BulkCreate, Title: "Rolled", Content: "Back"},
+		{Action: BulkDelete, ID: 99},
+	}, true)
+	repo.BulkWrite(ctx, []BulkOperation{
+		{Action: BulkCreate, Title: "Second", Content: "Two"},
+		{Action: BulkDelete, ID: 1},
+		{Action: BulkDelete, ID: 99},
+	}, false)
+	repo.ImportArticles(ctx, []models.Article{{ID: 2, Title: "Second v2", Content: "Two again"}}, ImportUpsert)
+
+	types := make([]string, len(published))
+	for i, event := range published {
+		types[i] = event.Type()
+		assert.False(t, event.Time().IsZero())
+	}
+	assert.Equal(t, []string{
+		events.TypeArticleCreated,
+		events.TypeArticleUpdated,
+		events.TypeArticleCreated,
+		events.TypeArticleDeleted,
+		events.TypeArticleUpdated,
+	}, types)
+
+	assert.Equal(t, article, published[0].Subject())
+	assert.Equal(t, updated, published[1].Subject())
+	assert.Equal(t, article, published[1].(events.ArticleUpdated).Previous)
+	assert.Equal(t, updated, published[3].Subject())
+	assert.Equal(t, "Second", published[4].(events.ArticleUpdated).Previous.Title)
+	assert.Equal(t, "Second v2", published[4].Subject().Title)
+}
@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type BulkAction string
+
+const (
+	BulkCreate BulkAction = "create"
+	BulkUpdate BulkAction = "update"
+	BulkDelete BulkAction = "delete"
+)
+
+var (
+	// ErrInvalidOperation is returned for bulk operations that are malformed,
+	// such as an update without an ID.
+	ErrInvalidOperation = errors.New("invalid operation")
+	// ErrRolledBack marks operations that succeeded on their own but were
+	// discarded because another operation in an atomic batch failed.
+	ErrRolledBack = errors.New("rolled back")
+)
+
+// BulkOperation is one write of a batch. An update keeps the author and tags
+// when Author or Tags is nil, like UpdateArticle.
+type BulkOperation struct {
+	Action  BulkAction
+	ID      int
+	Title   string
+	Content string
+	Author  *string
+	Tags    *[]string
+}
+
+// BulkResult is the outcome of one operation. Article is the created,
+// updated or deleted article when Err is nil.
+type BulkResult struct {
+	Article models.Article
+	Err     error
+}
+
+func (op BulkOperation) changes() ArticleChanges {
+	return ArticleChanges{Title: op.Title, Content: op.Content, Author: op.Author, Tags: op.Tags}
+}
+
+func (op BulkOperation) validate() error {
+	switch op.Action {
+	case BulkCreate:
+		return nil
+	case BulkUpdate:
+		if op.ID < 1 {
+			return fmt.Errorf("%w: update requires an id", ErrInvalidOperation)
+		}
+		if op.Title == "" || op.Content == "" {
+			return fmt.Errorf("%w: update requires title and content", ErrInvalidOperation)
+		}
+		return nil
+	case BulkDelete:
+		if op.ID < 1 {
+			return fmt.Errorf("%w: delete requires an id", ErrInvalidOperation)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidOperation, op.Action)
+	}
+}
+
+// BulkWrite applies ops in order while holding the write lock once, so no
+// other write is interleaved. Later operations see the effect of earlier
+// ones. When atomic is set and any operation fails, nothing is applied and
+// the operations that would have succeeded report ErrRolledBack. committed
+// reports whether the changes were kept. An error means the batch could not
+// be persisted and nothing was applied.
+func (r *ArticleRepository) BulkWrite(ctx context.Context, ops []BulkOperation, atomic bool) (results []BulkResult, committed bool, err error) {
+	done := r.instrument(ctx, "bulk", "BulkWrite", attribute.Int("repository.bulk.operations", len(ops)))
+	var emitted []events.Event
+	r.mu.Lock()
+	defer r.unlockAndPublish(ctx, &emitted)
+
+	// Work on a copy so an atomic batch can be dropped without undoing it.
+	articles := append([]models.Article{}, r.articles...)
+	nextID := r.nextID
+	index := make(map[int]int, len(articles))
+	for i, article := range articles {
+		index[article.ID] = i
+	}
+	deleted := map[int]bool{}
+	var logged []walOp
+	var pending []events.Event
+	now := time.Now()
+
+	results = make([]BulkResult, len(ops))
+	failed := false
+	for i, op := range ops {
+		if err := op.validate(); err != nil {
+			results[i].Err = err
+			failed = true
+			continue
+		}
+
+		if op.Action == BulkCreate {
+			article := op.changes().apply(models.Article{ID: nextID})
+			index[article.ID] = len(articles)
+			articles = append(articles, article)
+			nextID++
+			results[i].Article = article
+			logged = append(logged, createOp(article))
+			pending = append(pending, events.ArticleCreated{Article: article, At: now})
+			continue
+		}
+
+		pos, exists := index[op.ID]
+		if !exists {
+			results[i].Err = ErrNotFound
+			failed = true
+			continue
+		}
+		if op.Action == BulkUpdate {
+			previous := articles[pos]
+			articles[pos] = op.changes().apply(articles[pos])
+			logged = append(logged, updateOp(articles[pos]))
+			pending = append(pending, events.ArticleUpdated{Article: articles[pos], Previous: previous, At: now})
+		} else {
+			delete(index, op.ID)
+			deleted[pos] = true
+			logged = append(logged, deleteOp(op.ID))
+			pending = append(pending, events.ArticleDeleted{Article: articles[pos], At: now})
+		}
+		results[i].Article = articles[pos]
+	}
+
+	if atomic && failed {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = BulkResult{Err: ErrRolledBack}
+			}
+		}
+		done(ErrRolledBack)
+		return results, false, nil
+	}
+
+	if err := r.log(logged...); err != nil {
+		done(err)
+		return nil, false, err
+	}
+
+	if len(deleted) > 0 {
+		kept := articles[:0]
+		for i, article := range articles {
+			if !deleted[i] {
+				kept = append(kept, article)
+			}
+		}
+		articles = kept
+	}
+	r.articles = articles
+	r.nextID = nextID
+	emitted = pending
+
+	done(nil)
+	return results, true, nil
+}
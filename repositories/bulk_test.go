@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brothergiez/restful-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkWrite(t *testing.T) {
+	repo := NewArticleRepository()
+	repo.CreateArticle(context.Background(), models.Article{Title: "First", Content: "One"})
+	repo.CreateArticle(context.Background(), models.Article{Title: "Second", Content: "Two"})
+
+	results, committed, err := repo.BulkWrite(context.Background(), []BulkOperation{
+		{Action: BulkCreate, Title: "Third", Content: "Three", Tags: &[]string{"go"}},
+		{Action: BulkUpdate, ID: 3, Title: "Third v2", Content: "Three again"},
+		{Action: BulkDelete, ID: 1},
+		{Action: BulkDelete, ID: 1},
+		{Action: BulkUpdate, ID: 2},
+		{Action: "archive", ID: 2},
+	}, false)
+	assert.NoError(t, err)
+
+	assert.True(t, committed)
+	assert.Equal(t, models.Article{ID: 3, Title: "Third", Content: "Three", Tags: []string{"go"}}, results[0].Article)
+	// The update leaves out the tags, so they are kept.
+	assert.Equal(t, models.Article{ID: 3, Title: "Third v2", Content: "Three again", Tags: []string{"go"}}, results[1].Article)
+	assert.Equal(t, "First", results[2].Article.Title)
+	assert.ErrorIs(t, results[3].Err, ErrNotFound)
+	assert.ErrorIs(t, results[4].Err, ErrInvalidOperation)
+	assert.ErrorIs(t, results[5].Err, ErrInvalidOperation)
+
+	assert.Equal(t, []models.Article{
+		{ID: 2, Title: "Second", Content: "Two"},
+		{ID: 3, Title: "Third v2", Content: "Three again", Tags: []string{"go"}},
+	}, repo.articles)
+	next, err := repo.CreateArticle(context.Background(), models.Article{Title: "Fourth", Content: "Four"})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, next.ID)
+}
+
+func TestBulkWriteAtomic(t *testing.T) {
+	repo := NewArticleRepository()
+	repo.CreateArticle(context.Background(), models.Article{Title: "First", Content: "One"})
+
+	results, committed, err := repo.BulkWrite(context.Background(), []BulkOperation{
+		{Action: BulkCreate, Title: "Second", Content: "Two"},
+		{Action: BulkDelete, ID: 1},
+		{Action: BulkUpdate, ID: 99, Title: "Missing", Content: "Missing"},
+	}, true)
+	assert.NoError(t, err)
+
+	assert.False(t, committed)
+	assert.ErrorIs(t, results[0].Err, ErrRolledBack)
+	assert.ErrorIs(t, results[1].Err, ErrRolledBack)
+	assert.ErrorIs(t, results[2].Err, ErrNotFound)
+	assert.Equal(t, []models.Article{{ID: 1, Title: "First", Content: "One"}}, repo.articles)
+	assert.Equal(t, 2, repo.nextID)
+
+	results, committed, err = repo.BulkWrite(context.Background(), []BulkOperation{
+		{Action: BulkCreate, Title: "Second", Content: "Two"},
+		{Action: BulkDelete, ID: 1},
+	}, true)
+	assert.NoError(t, err)
+
+	assert.True(t, committed)
+	assert.NoError(t, results[0].Err)
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, []models.Article{{ID: 2, Title: "Second", Content: "Two"}}, repo.articles)
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type ImportMode string
+
+const (
+	// ImportAppend stores every imported article under a new ID.
+	ImportAppend ImportMode = "append"
+	// ImportUpsert keeps the imported IDs, replacing existing articles.
+	ImportUpsert ImportMode = "upsert"
+)
+
+// ImportResult counts what ImportArticles did with a batch.
+type ImportResult struct {
+	Created int
+	Updated int
+}
+
+// ArticlesAfter returns up to limit articles with an ID greater than afterID,
+// in ID order. Callers page through the store by passing the last ID they
+// received, so only one page is held in memory and the lock is released
+// between pages. Articles created while paging are included if their ID is
+// past the cursor.
+func (r *ArticleRepository) ArticlesAfter(ctx context.Context, afterID, limit int) []models.Article {
+	done := r.instrument(ctx, "export", "ArticlesAfter")
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	defer done(nil)
+
+	start := sort.Search(len(r.articles), func(i int) bool { return r.articles[i].ID > afterID })
+	end := min(start+limit, len(r.articles))
+	return append([]models.Article{}, r.articles[start:end]...)
+}
+
+// ImportArticles stores a batch of articles under a single lock. In append
+// mode their IDs are ignored; in upsert mode an article replaces the one with
+// the same ID or is inserted under that ID. The batch is logged as one record,
+// so on a persistent repository it is stored whole or not at all.
+func (r *ArticleRepository) ImportArticles(ctx context.Context, articles []models.Article, mode ImportMode) (ImportResult, error) {
+	done := r.instrument(ctx, "import", "ImportArticles",
+		attribute.String("repository.import.mode", string(mode)),
+		attribute.Int("repository.import.articles", len(articles)))
+	var emitted []events.Event
+	r.mu.Lock()
+	defer r.unlockAndPublish(ctx, &emitted)
+
+	var result ImportResult
+	ops := make([]walOp, len(articles))
+	pending := make([]events.Event, len(articles))
+	now := time.Now()
+	nextID := r.nextID
+	// stored holds the rows of this batch by ID, so an ID that repeats in the
+	// batch updates the earlier row.
+	stored := map[int]models.Article{}
+	for i, article := range articles {
+		if mode != ImportUpsert {
+			article.ID = nextID
+			nextID++
+		}
+		previous, exists := stored[article.ID]
+		if !exists {
+			pos := sort.Search(len(r.articles), func(i int) bool { return r.articles[i].ID >= article.ID })
+			if pos < len(r.articles) && r.articles[pos].ID == article.ID {
+				previous, exists = r.articles[pos], true
+			}
+		}
+		if exists {
+			ops[i] = updateOp(article)
+			pending[i] = events.ArticleUpdated{Article: article, Previous: previous, At: now}
+			result.Updated++
+		} else {
+			ops[i] = createOp(article)
+			pending[i] = events.ArticleCreated{Article: article, At: now}
+			result.Created++
+		}
+		stored[article.ID] = article
+	}
+
+	if err := r.log(ops...); err != nil {
+		done(err)
+		return ImportResult{}, err
+	}
+	for _, op := range ops {
+		r.apply(op)
+	}
+	emitted = pending
+
+	done(nil)
+	return result, nil
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArticlesAfter(t *testing.T) {
+	repo := NewArticleRepository()
+	for i := 0; i < 5; i++ {
+		repo.CreateArticle(context.Background(), models.Article{Title: "Title", Content: "Content"})
+	}
+	repo.BulkWrite(context.Background(), []BulkOperation{{Action: BulkDelete, ID: 2}}, false)
+
+	page := repo.ArticlesAfter(context.Background(), 0, 2)
+	assert.Equal(t, []int{1, 3}, articleIDs(page))
+
+	page = repo.ArticlesAfter(context.Background(), 3, 2)
+	assert.Equal(t, []int{4, 5}, articleIDs(page))
+
+	assert.Empty(t, repo.ArticlesAfter(context.Background(), 5, 2))
+}
+
+func TestImportArticles(t *testing.T) {
+	repo := NewArticleRepository()
+	repo.CreateArticle(context.Background(), models.Article{Title: "First", Content: "One"})
+	repo.CreateArticle(context.Background(), models.Article{Title: "Second", Content: "Two"})
+
+	result, err := repo.ImportArticles(context.Background(), []models.Article{
+		{ID: 2, Title: "Second v2", Content: "Two again"},
+		{ID: 10, Title: "Tenth", Content: "Ten"},
+		{ID: 5, Title: "Fifth", Content: "Five"},
+	}, ImportUpsert)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ImportResult{Created: 2, Updated: 1}, result)
+	assert.Equal(t, []int{1, 2, 5, 10}, articleIDs(repo.articles))
+	assert.Equal(t, "Second v2", repo.articles[1].Title)
+
+	result, err = repo.ImportArticles(context.Background(), []models.Article{
+		{ID: 1, Title: "Appended", Content: "New"},
+	}, ImportAppend)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ImportResult{Created: 1}, result)
+	assert.Equal(t, []int{1, 2, 5, 10, 11}, articleIDs(repo.articles))
+	assert.Equal(t, "First", repo.articles[0].Title)
+	next, err := repo.CreateArticle(context.Background(), models.Article{Title: "Next", Content: "Next"})
+	assert.NoError(t, err)
+	assert.Equal(t, 12, next.ID)
+}
+
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, emitted ...events.Event) {
+	p.events = append(p.events, emitted...)
+}
+
+func TestImportArticlesRepeatedIDs(t *testing.T) {
+	publisher := &recordingPublisher{}
+	repo := NewArticleRepository(WithPublisher(publisher))
+
+	result, err := repo.ImportArticles(context.Background(), []models.Article{
+		{ID: 3, Title: "Third", Content: "Three"},
+		{ID: 3, Title: "Third v2", Content: "Three again"},
+	}, ImportUpsert)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ImportResult{Created: 1, Updated: 1}, result)
+	assert.Equal(t, []int{3}, articleIDs(repo.articles))
+	assert.Equal(t, "Third v2", repo.articles[0].Title)
+	if assert.Len(t, publisher.events, 2) {
+		assert.Equal(t, events.TypeArticleCreated, publisher.events[0].Type())
+		update := publisher.events[1].(events.ArticleUpdated)
+		assert.Equal(t, "Third", update.Previous.Title)
+		assert.Equal(t, "Third v2", update.Article.Title)
+	}
+}
+
+func articleIDs(articles []models.Article) []int {
+	ids := []int{}
+	for _, article := range articles {
+		ids = append(ids, article.ID)
+	}
+	return ids
+}
@@ -0,0 +1,388 @@
+package repositories
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/brothergiez/restful-api/models"
+)
+
+type FsyncPolicy string
+
+const (
+	// FsyncAlways syncs the log before a write returns. No acknowledged write
+	// is lost, at the cost of one fsync per write.
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncInterval syncs the log every FsyncInterval, so a crash loses at
+	// most that much acknowledged data.
+	FsyncInterval FsyncPolicy = "interval"
+	// FsyncNever leaves syncing to the operating system.
+	FsyncNever FsyncPolicy = "never"
+)
+
+const (
+	walFile      = "articles.wal"
+	snapshotFile = "articles.snapshot.json"
+)
+
+type PersistenceConfig struct {
+	// Dir holds the write-ahead log and the snapshot. It is created if needed.
+	Dir           string
+	Fsync         FsyncPolicy
+	FsyncInterval time.Duration
+	// SnapshotInterval is how often the store is written to a snapshot and
+	// the log is truncated. Zero snapshots only on Close.
+	SnapshotInterval time.Duration
+}
+
+func DefaultPersistenceConfig() PersistenceConfig {
+	return PersistenceConfig{
+		Fsync:            FsyncAlways,
+		FsyncInterval:    time.Second,
+		SnapshotInterval: 5 * time.Minute,
+	}
+}
+
+// walOp is one change in the log. Creates and updates carry the whole
+// article as stored, so replaying them is the same as storing it again.
+type walOp struct {
+	Op      string          `json:"op"`
+	Article *models.Article `json:"article,omitempty"`
+	ID      int             `json:"id,omitempty"`
+}
+
+// walRecord is one line of the log. All ops of a record come from a single
+// repository call, so a bulk write is replayed whole or not at all.
+type walRecord struct {
+	Seq uint64  `json:"seq"`
+	Ops []walOp `json:"ops"`
+}
+
+type snapshot struct {
+	// Seq is the last log record included in the snapshot. Records up to it
+	// are skipped on replay, which makes a crash between writing the snapshot
+	// and truncating the log harmless.
+	Seq      uint64           `json:"seq"`
+	NextID   int              `json:"nextId"`
+	Articles []models.Article `json:"articles"`
+}
+
+func createOp(article models.Article) walOp {
+	return walOp{Op: "create", Article: &article}
+}
+
+func updateOp(article models.Article) walOp {
+	return walOp{Op: "update", Article: &article}
+}
+
+func deleteOp(id int) walOp {
+	return walOp{Op: "delete", ID: id}
+}
+
+// persistence owns the log file. Appends and snapshots run under the
+// repository's write lock, which orders them.
+type persistence struct {
+	config  PersistenceConfig
+	wal     *os.File
+	seq     uint64
+	size    int64 // length of the log up to the last complete record
+	pending int   // records since the last snapshot
+	closed  bool
+
+	dirty       atomic.Bool
+	syncErr     atomic.Pointer[error]
+	snapshotErr atomic.Pointer[error]
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// OpenArticleRepository returns a repository backed by a write-ahead log and
+// snapshots in config.Dir. The stored articles are restored before it
+// returns. Close must be called to stop the background work and write a
+// final snapshot.
+func OpenArticleRepository(config PersistenceConfig, opts ...Option) (*ArticleRepository, error) {
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create data directory: %w", err)
+	}
+
+	r := NewArticleRepository(opts...)
+	p := &persistence{config: config, stop: make(chan struct{})}
+
+	snap, err := readSnapshot(filepath.Join(config.Dir, snapshotFile))
+	if err != nil {
+		return nil, err
+	}
+	if snap.Articles != nil {
+		r.articles = snap.Articles
+		r.nextID = snap.NextID
+	}
+	p.seq = snap.Seq
+
+	p.wal, err = os.OpenFile(filepath.Join(config.Dir, walFile), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open write-ahead log: %w", err)
+	}
+	if err := p.replay(r); err != nil {
+		p.wal.Close()
+		return nil, err
+	}
+
+	r.persist = p
+	p.start(r)
+	return r, nil
+}
+
+func readSnapshot(path string) (snapshot, error) {
+	var snap snapshot
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return snap, nil
+	}
+	if err != nil {
+		return snap, fmt.Errorf("read snapshot: %w", err)
+	}
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return snap, fmt.Errorf("parse snapshot %s: %w", path, err)
+	}
+	if snap.Articles == nil {
+		snap.Articles = []models.Article{}
+	}
+	return snap, nil
+}
+
+// replay applies the log records written after the snapshot. A last line
+// without a newline is what a crash during an append leaves behind; it was
+// never acknowledged, so it is cut off. Any other unreadable line is
+// corruption and stops the start.
+func (p *persistence) replay(r *ArticleRepository) error {
+	reader := bufio.NewReader(p.wal)
+	var offset int64
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				if err := p.wal.Truncate(offset); err != nil {
+					return fmt.Errorf("truncate torn write-ahead log record: %w", err)
+				}
+			}
+			p.size = offset
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read write-ahead log: %w", err)
+		}
+		offset += int64(len(line))
+
+		var record walRecord
+		if err := json.Unmarshal(bytes.TrimSpace(line), &record); err != nil {
+			return fmt.Errorf("write-ahead log line %d is corrupt: %w", lineNumber, err)
+		}
+		if record.Seq <= p.seq {
+			continue
+		}
+		for _, op := range record.Ops {
+			r.apply(op)
+		}
+		p.seq = record.Seq
+		p.pending++
+	}
+}
+
+// apply replays one logged change onto the in-memory store.
+func (r *ArticleRepository) apply(op walOp) {
+	switch op.Op {
+	case "create", "update":
+		r.upsert(*op.Article)
+	case "delete":
+		r.remove(op.ID)
+	}
+}
+
+func (p *persistence) start(r *ArticleRepository) {
+	if p.config.Fsync == FsyncInterval && p.config.FsyncInterval > 0 {
+		p.every(p.config.FsyncInterval, func() {
+			if p.dirty.Swap(false) {
+				if err := p.wal.Sync(); err != nil {
+					p.dirty.Store(true)
+					p.syncErr.Store(&err)
+					return
+				}
+				p.syncErr.Store(nil)
+			}
+		})
+	}
+	if p.config.SnapshotInterval > 0 {
+		p.every(p.config.SnapshotInterval, func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if err := p.snapshot(r); err != nil {
+				p.snapshotErr.Store(&err)
+				return
+			}
+			// The snapshot holds every write and the log was synced after
+			// it, so an earlier sync failure no longer matters either.
+			p.snapshotErr.Store(nil)
+			p.syncErr.Store(nil)
+		})
+	}
+}
+
+func (p *persistence) every(interval time.Duration, fn func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+}
+
+// append writes ops as one record. It is called with the repository's write
+// lock held and before the change is applied in memory, so a failed append
+// leaves the store unchanged.
+func (p *persistence) append(ops []walOp) error {
+	if p.closed {
+		return ErrClosed
+	}
+	line, err := json.Marshal(walRecord{Seq: p.seq + 1, Ops: ops})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	// A record that was not fully written or synced is cut off again, so it
+	// can neither corrupt the next record nor be replayed after the caller
+	// was told the write failed.
+	if _, err := p.wal.Write(line); err != nil {
+		_ = p.wal.Truncate(p.size)
+		return fmt.Errorf("append to write-ahead log: %w", err)
+	}
+	if p.config.Fsync == FsyncAlways {
+		if err := p.wal.Sync(); err != nil {
+			_ = p.wal.Truncate(p.size)
+			return fmt.Errorf("sync write-ahead log: %w", err)
+		}
+	} else {
+		p.dirty.Store(true)
+	}
+	p.size += int64(len(line))
+	p.seq++
+	p.pending++
+	return nil
+}
+
+// snapshot writes the whole store next to the log and then empties the log.
+// It is called with the repository's write lock held.
+func (p *persistence) snapshot(r *ArticleRepository) error {
+	if p.pending == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(snapshot{Seq: p.seq, NextID: r.nextID, Articles: r.articles})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(p.config.Dir, snapshotFile), data); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+
+	if err := p.wal.Truncate(0); err != nil {
+		return fmt.Errorf("truncate write-ahead log: %w", err)
+	}
+	p.size = 0
+	if err := p.wal.Sync(); err != nil {
+		return fmt.Errorf("sync write-ahead log: %w", err)
+	}
+	p.pending = 0
+	return nil
+}
+
+// err reports a failure from the last background sync or snapshot. It is
+// cleared once a later one succeeds.
+func (p *persistence) err() error {
+	if err := p.syncErr.Load(); err != nil {
+		return *err
+	}
+	if err := p.snapshotErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// close stops the background work. The caller then takes the write lock and
+// calls finish.
+func (p *persistence) close() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+// finish writes the final snapshot and closes the log. It is called with the
+// repository's write lock held.
+func (p *persistence) finish(r *ArticleRepository) error {
+	err := p.snapshot(r)
+	if syncErr := p.wal.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := p.wal.Close(); err == nil {
+		err = closeErr
+	}
+	p.closed = true
+	return err
+}
+
+// writeFileAtomic replaces path with data so that a crash leaves either the
+// old or the new file, never a partial one.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// log records ops when the repository is persistent. It must be called with
+// the write lock held, before the change is applied.
+func (r *ArticleRepository) log(ops ...walOp) error {
+	if r.persist == nil || len(ops) == 0 {
+		return nil
+	}
+	return r.persist.append(ops)
+}
@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brothergiez/restful-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestRepository(t *testing.T, dir string) *ArticleRepository {
+	config := DefaultPersistenceConfig()
+	config.Dir = dir
+	config.SnapshotInterval = 0
+	repo, err := OpenArticleRepository(config)
+	assert.NoError(t, err)
+	return repo
+}
+
+func TestPersistenceReplaysLogAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	repo := openTestRepository(t, dir)
+	_, err := repo.CreateArticle(ctx, models.Article{Title: "First", Content: "One"})
+	assert.NoError(t, err)
+	_, err = repo.CreateArticle(ctx, models.Article{Title: "Second", Content: "Two"})
+	assert.NoError(t, err)
+	_, err = repo.UpdateArticle(ctx, 1, ArticleChanges{Title: "First v2", Content: "One again"})
+	assert.NoError(t, err)
+	_, _, err = repo.BulkWrite(ctx, []BulkOperation{
+		{Action: BulkCreate, Title: "Third", Content: "Three"},
+		{Action: BulkDelete, ID: 2},
+		{Action: BulkDelete, ID: 42},
+	}, false)
+	assert.NoError(t, err)
+	_, err = repo.ImportArticles(ctx, []models.Article{{ID: 10, Title: "Tenth", Content: "Ten"}}, ImportUpsert)
+	assert.NoError(t, err)
+
+	// The first repository is never closed, as after a crash.
+	restored := openTestRepository(t, dir)
+	assert.Equal(t, repo.articles, restored.articles)
+	assert.Equal(t, 11, restored.nextID)
+}
+
+func TestPersistenceSnapshotOnClose(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	repo := openTestRepository(t, dir)
+	repo.CreateArticle(ctx, models.Article{Title: "First", Content: "One"})
+	repo.CreateArticle(ctx, models.Article{Title: "Second", Content: "Two"})
+	repo.BulkWrite(ctx, []BulkOperation{{Action: BulkDelete, ID: 2}}, false)
+	assert.NoError(t, repo.Close(ctx))
+
+	info, err := os.Stat(filepath.Join(dir, walFile))
+	assert.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	_, err = repo.CreateArticle(ctx, models.Article{Title: "Late", Content: "Write"})
+	assert.ErrorIs(t, err, ErrClosed)
+
+	restored := openTestRepository(t, dir)
+	assert.Equal(t, []models.Article{{ID: 1, Title: "First", Content: "One"}}, restored.articles)
+
+	// The ID of the deleted article is not handed out again.
+	article, err := restored.CreateArticle(ctx, models.Article{Title: "Third", Content: "Three"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, article.ID)
+}
+
+func TestPersistenceTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	repo := openTestRepository(t, dir)
+	repo.CreateArticle(ctx, models.Article{Title: "First", Content: "One"})
+
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_WRONLY|os.O_APPEND, 0)
+	assert.NoError(t, err)
+	_, err = wal.WriteString(`{"seq":2,"ops":[{"op":"create","arti`)
+	assert.NoError(t, err)
+	wal.Close()
+
+	restored := openTestRepository(t, dir)
+	assert.Len(t, restored.articles, 1)
+	restored.CreateArticle(ctx, models.Article{Title: "Second", Content: "Two"})
+
+	restored = openTestRepository(t, dir)
+	assert.Equal(t, []int{1, 2}, articleIDs(restored.articles))
+}
+
+func TestPersistenceCorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, walFile), []byte("garbage\n{\"seq\":1,\"ops\":[]}\n"), 0o644))
+
+	_, err := OpenArticleRepository(PersistenceConfig{Dir: dir, Fsync: FsyncAlways})
+	assert.ErrorContains(t, err, "line 1 is corrupt")
+}
+
+func TestPersistenceSkipsRecordsInSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	repo := openTestRepository(t, dir)
+	repo.CreateArticle(ctx, models.Article{Title: "Draft", Content: "One"})
+	firstRecord, err := os.ReadFile(filepath.Join(dir, walFile))
+	assert.NoError(t, err)
+	repo.UpdateArticle(ctx, 1, ArticleChanges{Title: "Final", Content: "One"})
+
+	repo.mu.Lock()
+	assert.NoError(t, repo.persist.snapshot(repo))
+	repo.mu.Unlock()
+
+	// A crash after the snapshot but before the truncation leaves old
+	// records behind.
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, walFile), firstRecord, 0o644))
+
+	restored := openTestRepository(t, dir)
+	assert.Equal(t, []models.Article{{ID: 1, Title: "Final", Content: "One"}}, restored.articles)
+}
+
+func TestPersistencePeriodicSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	config := PersistenceConfig{
+		Dir:              dir,
+		Fsync:            FsyncInterval,
+		FsyncInterval:    5 * time.Millisecond,
+		SnapshotInterval: 20 * time.Millisecond,
+	}
+	repo, err := OpenArticleRepository(config)
+	assert.NoError(t, err)
+	defer repo.Close(context.Background())
+
+	repo.CreateArticle(context.Background(), models.Article{Title: "First", Content: "One"})
+
+	assert.Eventually(t, func() bool {
+		info, err := os.Stat(filepath.Join(dir, walFile))
+		return err == nil && info.Size() == 0
+	}, time.Second, 5*time.Millisecond)
+
+	snap, err := readSnapshot(filepath.Join(dir, snapshotFile))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), snap.Seq)
+	assert.Equal(t, 2, snap.NextID)
+	assert.Len(t, snap.Articles, 1)
+	assert.NoError(t, repo.Ping(context.Background()))
+}
+
+func TestPersistenceRecoversFromSnapshotFailure(t *testing.T) {
+	dir := t.TempDir()
+	config := PersistenceConfig{Dir: dir, Fsync: FsyncAlways, SnapshotInterval: 5 * time.Millisecond}
+	repo, err := OpenArticleRepository(config)
+	assert.NoError(t, err)
+	defer repo.Close(context.Background())
+
+	// A directory in the way of the temporary file makes snapshots fail.
+	blocker := filepath.Join(dir, snapshotFile+".tmp")
+	assert.NoError(t, os.MkdirAll(filepath.Join(blocker, "x"), 0o755))
+	repo.CreateArticle(context.Background(), models.Article{Title: "First", Content: "One"})
+	assert.Eventually(t, func() bool {
+		return repo.Ping(context.Background()) != nil
+	}, time.Second, 5*time.Millisecond)
+
+	assert.NoError(t, os.RemoveAll(blocker))
+	assert.Eventually(t, func() bool {
+		return repo.Ping(context.Background()) == nil
+	}, time.Second, 5*time.Millisecond)
+	snap, err := readSnapshot(filepath.Join(dir, snapshotFile))
+	assert.NoError(t, err)
+	assert.Len(t, snap.Articles, 1)
+}
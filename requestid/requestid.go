@@ -0,0 +1,58 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Header is the HTTP header used to receive and echo the request ID.
+const Header = "X-Request-ID"
+
+// GinKey is the key the request ID is stored under in the gin context.
+const GinKey = "requestID"
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or an empty string.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Generate returns a random 128-bit ID encoded as hex.
+func Generate() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Valid reports whether a client supplied ID is safe to reuse: non-empty, at
+// most 128 characters and limited to printable ASCII without spaces.
+func Valid(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// ErrorBody builds the JSON error body used across the API, tagged with the
+// request ID when one is set.
+func ErrorBody(c *gin.Context, message string) gin.H {
+	body := gin.H{"error": message}
+	if id := c.GetString(GinKey); id != "" {
+		body["requestId"] = id
+	}
+	return body
+}
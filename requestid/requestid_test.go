@@ -0,0 +1,41 @@
+package requestid
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext(t *testing.T) {
+	assert.Equal(t, "", FromContext(context.Background()))
+
+	ctx := NewContext(context.Background(), "abc-123")
+	assert.Equal(t, "abc-123", FromContext(ctx))
+}
+
+func TestGenerate(t *testing.T) {
+	id := Generate()
+	assert.Len(t, id, 32)
+	assert.True(t, Valid(id))
+	assert.NotEqual(t, id, Generate())
+}
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid("client-request-1"))
+	assert.False(t, Valid(""))
+	assert.False(t, Valid("has space"))
+	assert.False(t, Valid("line\nbreak"))
+	assert.False(t, Valid(strings.Repeat("a", 129)))
+}
+
+func TestErrorBody(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.Equal(t, gin.H{"error": "Invalid ID"}, ErrorBody(c, "Invalid ID"))
+
+	c.Set(GinKey, "abc-123")
+	assert.Equal(t, gin.H{"error": "Invalid ID", "requestId": "abc-123"}, ErrorBody(c, "Invalid ID"))
+}
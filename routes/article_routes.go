@@ -6,12 +6,19 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterArticleRoutes(router *gin.Engine, handler *handlers.ArticleHandler) {
-	articleRoutes := router.Group("/articles")
+// RegisterArticleRoutes registers the article routes. Any middleware given,
+// such as idempotency, applies to the article routes only.
+func RegisterArticleRoutes(router *gin.Engine, handler *handlers.ArticleHandler, middleware ...gin.HandlerFunc) {
+	articleRoutes := router.Group("/articles", middleware...)
 	{
 		articleRoutes.POST("/create", handler.CreateArticleHandler)
 		articleRoutes.PUT("/update/:id", handler.UpdateArticleHandler)
 		articleRoutes.GET("/search", handler.SearchArticlesHandler)
 		articleRoutes.GET("/get-all", handler.GetAllArticlesHandler)
+		articleRoutes.POST("/bulk", handler.BulkArticlesHandler)
+		articleRoutes.GET("/export", handler.ExportArticlesHandler)
+		articleRoutes.POST("/import", handler.ImportArticlesHandler)
+		articleRoutes.GET("/stream", handler.StreamArticlesHandler)
+		articleRoutes.GET("/presence/:id", handler.ArticlePresenceHandler)
 	}
 }
@@ -140,3 +140,16 @@ func TestRegisterArticleRoutes_GetAllArticles(t *testing.T) {
 	assert.Equal(t, "Title 1", result.Articles[0]["title"])
 	assert.Equal(t, "Title 5", result.Articles[4]["title"])
 }
+
+func TestRegisterArticleRoutes_BulkArticles(t *testing.T) {
+	router := setupRouter()
+
+	payload := `{"operations":[{"action":"create","title":"Bulk Title","content":"Bulk Content"}]}`
+	req, _ := http.NewRequest(http.MethodPost, "/articles/bulk", bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"title":"Bulk Title"`)
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/brothergiez/restful-api/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterDocsRoutes serves the OpenAPI document and the Swagger UI page
+// with its files. They need no API key, so the page can load before one is
+// entered.
+func RegisterDocsRoutes(router *gin.Engine, handler *handlers.DocsHandler) {
+	router.GET("/openapi.json", handler.OpenAPIHandler)
+	router.GET("/docs", handler.SwaggerUIHandler)
+	router.GET("/docs/*filepath", handler.SwaggerUIAssetHandler)
+}
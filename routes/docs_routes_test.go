@@ -0,0 +1,54 @@
+package routes
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/brothergiez/restful-api/handlers"
+	"github.com/brothergiez/restful-api/repositories"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var ginParam = regexp.MustCompile(`:([^/]+)`)
+
+// TestOpenAPISpecCoversArticleRoutes fails when an article route is added or
+// removed without updating handlers.OpenAPISpec.
+func TestOpenAPISpecCoversArticleRoutes(t *testing.T) {
+	router := gin.New()
+	RegisterArticleRoutes(router, handlers.NewArticleHandler(repositories.NewArticleRepository()))
+	doc, err := handlers.OpenAPISpec()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		registered[route.Method+" "+path] = true
+		item := doc.Paths.Value(path)
+		if assert.NotNil(t, item, "%s %s has no OpenAPI entry", route.Method, path) {
+			assert.NotNil(t, item.GetOperation(route.Method), "%s %s has no OpenAPI entry", route.Method, path)
+		}
+	}
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			assert.True(t, registered[method+" "+path], "%s %s is documented but not registered", method, path)
+		}
+	}
+}
+
+func TestRegisterDocsRoutes(t *testing.T) {
+	handler, err := handlers.NewDocsHandler()
+	if !assert.NoError(t, err) {
+		return
+	}
+	router := gin.New()
+	RegisterDocsRoutes(router, handler)
+
+	paths := map[string]bool{}
+	for _, route := range router.Routes() {
+		paths[route.Method+" "+route.Path] = true
+	}
+	assert.Equal(t, map[string]bool{"GET /openapi.json": true, "GET /docs": true, "GET /docs/*filepath": true}, paths)
+}
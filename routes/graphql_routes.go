@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/brothergiez/restful-api/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterGraphQLRoutes registers the GraphQL endpoint. Any middleware given,
+// such as authentication, applies to it only.
+func RegisterGraphQLRoutes(router *gin.Engine, handler *handlers.GraphQLHandler, middleware ...gin.HandlerFunc) {
+	graphQLRoutes := router.Group("/graphql", middleware...)
+	{
+		graphQLRoutes.GET("", handler.ServeGraphQL)
+		graphQLRoutes.POST("", handler.ServeGraphQL)
+	}
+}
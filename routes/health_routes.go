@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/brothergiez/restful-api/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthPaths are the probe endpoints, which are excluded from request logs.
+var HealthPaths = []string{"/healthz", "/readyz", "/version"}
+
+func RegisterHealthRoutes(router *gin.Engine, handler *handlers.HealthHandler) {
+	router.GET("/healthz", handler.LivenessHandler)
+	router.GET("/readyz", handler.ReadinessHandler)
+	router.GET("/version", handler.VersionHandler)
+}
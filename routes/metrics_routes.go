@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/brothergiez/restful-api/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterMetricsRoutes(router *gin.Engine, m *metrics.Metrics) {
+	router.GET("/metrics", gin.WrapH(m.Handler()))
+}
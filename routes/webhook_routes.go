@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/brothergiez/restful-api/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterWebhookRoutes registers the webhook subscription routes. Any
+// middleware given, such as authentication, applies to these routes only.
+func RegisterWebhookRoutes(router *gin.Engine, handler *handlers.WebhookHandler, middleware ...gin.HandlerFunc) {
+	webhookRoutes := router.Group("/webhooks", middleware...)
+	{
+		webhookRoutes.POST("", handler.CreateWebhookHandler)
+		webhookRoutes.GET("", handler.ListWebhooksHandler)
+		webhookRoutes.GET("/dead-letters", handler.ListDeadLettersHandler)
+		webhookRoutes.POST("/dead-letters/:id/retry", handler.RetryDeadLetterHandler)
+		webhookRoutes.GET("/:id", handler.GetWebhookHandler)
+		webhookRoutes.DELETE("/:id", handler.DeleteWebhookHandler)
+		webhookRoutes.GET("/:id/deliveries", handler.WebhookDeliveriesHandler)
+	}
+}
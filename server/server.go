@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+type Config struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownTimeout bounds how long in-flight requests and shutdown hooks
+	// get to finish once a stop signal is received.
+	ShutdownTimeout time.Duration
+	// TLS serves HTTPS, with HTTP/2 negotiated over ALPN, when a certificate
+	// is configured.
+	TLS TLSConfig
+	// H2C accepts HTTP/2 without TLS, for internal traffic behind a trusted
+	// network. It is ignored when TLS is enabled.
+	H2C bool
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Addr:              ":8080",
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		ShutdownTimeout:   20 * time.Second,
+	}
+}
+
+// ShutdownHook releases a resource once the server has stopped accepting
+// requests, e.g. flushing a repository or an exporter.
+type ShutdownHook struct {
+	Name string
+	Func func(ctx context.Context) error
+}
+
+type Server struct {
+	config Config
+	http   *http.Server
+	logger *slog.Logger
+	hooks  []ShutdownHook
+}
+
+// New builds the server. It fails when the TLS certificate or client CA
+// cannot be loaded.
+func New(handler http.Handler, config Config, logger *slog.Logger) (*Server, error) {
+	httpServer := &http.Server{
+		Addr:              config.Addr,
+		Handler:           handler,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+	}
+
+	if config.TLS.Enabled() {
+		tlsConfig, err := newTLSConfig(config.TLS)
+		if err != nil {
+			return nil, err
+		}
+		httpServer.TLSConfig = tlsConfig
+	} else if config.H2C {
+		httpServer.Handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: config.IdleTimeout})
+	}
+
+	return &Server{
+		config: config,
+		logger: logger,
+		http:   httpServer,
+	}, nil
+}
+
+// TLSConfig returns the TLS configuration of the server, or nil when TLS is
+// not enabled. Other listeners can use it to share the certificate and its
+// reloading.
+func (s *Server) TLSConfig() *tls.Config {
+	return s.http.TLSConfig
+}
+
+// OnShutdown registers a hook. Hooks run in reverse registration order after
+// in-flight requests have drained, so resources opened first close last.
+func (s *Server) OnShutdown(name string, hook func(ctx context.Context) error) {
+	s.hooks = append(s.hooks, ShutdownHook{Name: name, Func: hook})
+}
+
+// OnDrain registers fn to run as soon as shutdown starts, while in-flight
+// requests are still draining. Long-lived responses such as event streams use
+// it to end themselves instead of holding the shutdown until its timeout.
+func (s *Server) OnDrain(fn func()) {
+	s.http.RegisterOnShutdown(fn)
+}
+
+// Run listens on the configured address and serves until ctx is cancelled,
+// then shuts down gracefully.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves on listener until ctx is cancelled or the server fails. On
+// cancellation it stops accepting connections, waits up to ShutdownTimeout for
+// in-flight requests and then runs the shutdown hooks.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		if s.http.TLSConfig != nil {
+			s.logger.Info("Starting server", "addr", listener.Addr().String(), "tls", true)
+			serveErr <- s.http.ServeTLS(listener, "", "")
+			return
+		}
+		s.logger.Info("Starting server", "addr", listener.Addr().String(), "h2c", s.config.H2C)
+		serveErr <- s.http.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			s.runHooks(context.Background())
+			return err
+		}
+	case <-ctx.Done():
+		s.logger.Info("Shutting down server", "timeout", s.config.ShutdownTimeout.String())
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+
+	err := s.http.Shutdown(shutdownCtx)
+	if err != nil {
+		s.logger.Error("Server did not drain in time", "error", err)
+	}
+	if hookErr := s.runHooks(shutdownCtx); err == nil {
+		err = hookErr
+	}
+	return err
+}
+
+func (s *Server) runHooks(ctx context.Context) error {
+	var errs []error
+	for i := len(s.hooks) - 1; i >= 0; i-- {
+		hook := s.hooks[i]
+		if err := hook.Func(ctx); err != nil {
+			s.logger.Error("Shutdown hook failed", "hook", hook.Name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", hook.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerGracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+
+	config := DefaultConfig()
+	config.ShutdownTimeout = 2 * time.Second
+	srv, err := New(handler, config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.NoError(t, err)
+
+	var hooks []string
+	srv.OnShutdown("repository", func(ctx context.Context) error {
+		hooks = append(hooks, "repository")
+		return nil
+	})
+	srv.OnShutdown("tracing", func(ctx context.Context) error {
+		hooks = append(hooks, "tracing")
+		return errors.New("flush failed")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.Serve(ctx, listener) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	// The in-flight request still completes after shutdown has begun.
+	response := <-responses
+	assert.NoError(t, response.err)
+	assert.Equal(t, "done", response.body)
+
+	err = <-serveErr
+	assert.ErrorContains(t, err, "tracing: flush failed")
+	assert.Equal(t, []string{"tracing", "repository"}, hooks)
+
+	_, err = http.Get("http://" + listener.Addr().String())
+	assert.Error(t, err)
+}
+
+func TestServerOnDrain(t *testing.T) {
+	started := make(chan struct{})
+	drain := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		// A long-lived response that only ends when told to.
+		<-drain
+		w.Write([]byte("drained"))
+	})
+
+	config := DefaultConfig()
+	config.ShutdownTimeout = 5 * time.Second
+	srv, err := New(handler, config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.NoError(t, err)
+	srv.OnDrain(func() { close(drain) })
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.Serve(ctx, listener) }()
+
+	bodies := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			bodies <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		bodies <- string(body)
+	}()
+
+	<-started
+	start := time.Now()
+	cancel()
+	assert.NoError(t, <-serveErr)
+	assert.Less(t, time.Since(start), config.ShutdownTimeout)
+	assert.Equal(t, "drained", <-bodies)
+}
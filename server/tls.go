@@ -0,0 +1,146 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables mutual TLS: client certificates are verified
+	// against the CAs in this PEM file.
+	ClientCAFile string
+	// ClientAuth is "require" (default when ClientCAFile is set) or
+	// "verify_if_given".
+	ClientAuth string
+	// ReloadInterval is how often the certificate files are checked for
+	// changes. Zero disables reloading.
+	ReloadInterval time.Duration
+}
+
+// Enabled reports whether a certificate is configured.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// newTLSConfig builds the server TLS configuration. Certificates are served
+// through a certReloader so renewed files are picked up without a restart.
+func newTLSConfig(config TLSConfig) (*tls.Config, error) {
+	reloader, err := newCertReloader(config.CertFile, config.KeyFile, config.ReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+
+	if config.ClientCAFile != "" {
+		pem, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("client CA file contains no PEM certificates")
+		}
+		tlsConfig.ClientCAs = pool
+
+		switch config.ClientAuth {
+		case "", "require":
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		case "verify_if_given":
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		default:
+			return nil, fmt.Errorf("invalid client auth mode %q", config.ClientAuth)
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// certReloader serves a certificate loaded from disk and reloads it when the
+// certificate or key file changes. Files are checked lazily, at most once per
+// interval, during handshakes.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	lastCheck   time.Time
+}
+
+func newCertReloader(certFile, keyFile string, interval time.Duration) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, interval: interval}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.interval > 0 && time.Since(r.lastCheck) >= r.interval {
+		r.lastCheck = time.Now()
+		if r.changed() {
+			// Keep serving the previous certificate if the new files are
+			// unreadable, e.g. when only one of them has been replaced so far.
+			_ = r.load()
+		}
+	}
+	return r.cert, nil
+}
+
+func (r *certReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.load()
+}
+
+func (r *certReloader) load() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("stat certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("stat key: %w", err)
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	r.lastCheck = time.Now()
+	return nil
+}
+
+func (r *certReloader) changed() bool {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return false
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return false
+	}
+	return !certInfo.ModTime().Equal(r.certModTime) || !keyInfo.ModTime().Equal(r.keyModTime)
+}
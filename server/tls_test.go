@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert issues a certificate for commonName, signed by parent or
+// self-signed when parent is nil.
+func newTestCert(t *testing.T, commonName string, isCA bool, parent *testCert) testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	return testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeTestCert(t *testing.T, dir string, cert testCert) (string, string) {
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	assert.NoError(t, os.WriteFile(certFile, cert.certPEM, 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, cert.keyPEM, 0o600))
+	return certFile, keyFile
+}
+
+func startTestServer(t *testing.T, config Config) string {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})
+
+	srv, err := New(handler, config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		srv.Serve(ctx, listener)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return listener.Addr().String()
+}
+
+func get(t *testing.T, client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return string(body), nil
+}
+
+func TestServerTLSWithHTTP2(t *testing.T) {
+	ca := newTestCert(t, "test-ca", true, nil)
+	certFile, keyFile := writeTestCert(t, t.TempDir(), newTestCert(t, "localhost", false, &ca))
+
+	config := DefaultConfig()
+	config.TLS = TLSConfig{CertFile: certFile, KeyFile: keyFile}
+	addr := startTestServer(t, config)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		ForceAttemptHTTP2: true,
+	}}
+
+	proto, err := get(t, client, "https://"+addr)
+	assert.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0", proto)
+
+	_, err = get(t, http.DefaultClient, "http://"+addr)
+	assert.NoError(t, err, "plain HTTP requests get a 400 response rather than a transport error")
+}
+
+func TestServerCertificateReload(t *testing.T) {
+	ca := newTestCert(t, "test-ca", true, nil)
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, newTestCert(t, "first", false, &ca))
+
+	reloader, err := newCertReloader(certFile, keyFile, time.Nanosecond)
+	assert.NoError(t, err)
+
+	cert, err := reloader.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", cert.Leaf.Subject.CommonName)
+
+	writeTestCert(t, dir, newTestCert(t, "second", false, &ca))
+	future := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(certFile, future, future))
+	assert.NoError(t, os.Chtimes(keyFile, future, future))
+
+	cert, err = reloader.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", cert.Leaf.Subject.CommonName)
+
+	// A broken replacement keeps the previous certificate in service.
+	assert.NoError(t, os.WriteFile(keyFile, []byte("not a key"), 0o600))
+	later := future.Add(time.Minute)
+	assert.NoError(t, os.Chtimes(keyFile, later, later))
+
+	cert, err = reloader.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", cert.Leaf.Subject.CommonName)
+}
+
+func TestServerMutualTLS(t *testing.T) {
+	ca := newTestCert(t, "test-ca", true, nil)
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, newTestCert(t, "localhost", false, &ca))
+	caFile := filepath.Join(dir, "ca.crt")
+	assert.NoError(t, os.WriteFile(caFile, ca.certPEM, 0o600))
+
+	config := DefaultConfig()
+	config.TLS = TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile}
+	addr := startTestServer(t, config)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	_, err := get(t, anonymous, "https://"+addr)
+	assert.Error(t, err)
+
+	clientCert := newTestCert(t, "client", false, &ca)
+	keyPair, err := tls.X509KeyPair(clientCert.certPEM, clientCert.keyPEM)
+	assert.NoError(t, err)
+	authenticated := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{keyPair},
+	}}}
+	_, err = get(t, authenticated, "https://"+addr)
+	assert.NoError(t, err)
+}
+
+func TestServerH2C(t *testing.T) {
+	config := DefaultConfig()
+	config.H2C = true
+	addr := startTestServer(t, config)
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	proto, err := get(t, client, "http://"+addr)
+	assert.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0", proto)
+
+	proto, err = get(t, http.DefaultClient, "http://"+addr)
+	assert.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1", proto)
+}
+
+func TestNewInvalidTLS(t *testing.T) {
+	config := DefaultConfig()
+	config.TLS = TLSConfig{CertFile: "missing.crt", KeyFile: "missing.key"}
+
+	_, err := New(http.NotFoundHandler(), config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.Error(t, err)
+}
+
+func TestServerTLSConfig(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv, err := New(http.NotFoundHandler(), DefaultConfig(), logger)
+	assert.NoError(t, err)
+	assert.Nil(t, srv.TLSConfig())
+
+	certFile, keyFile := writeTestCert(t, t.TempDir(), newTestCert(t, "localhost", false, nil))
+	config := DefaultConfig()
+	config.TLS = TLSConfig{CertFile: certFile, KeyFile: keyFile}
+	srv, err = New(http.NotFoundHandler(), config, logger)
+	assert.NoError(t, err)
+	assert.NotNil(t, srv.TLSConfig().GetCertificate)
+}
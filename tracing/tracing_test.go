@@ -0,0 +1,36 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStdoutExporter(t *testing.T) {
+	var output bytes.Buffer
+	config := Config{Exporter: "stdout", ServiceName: "test", SampleRatio: 1}
+
+	exporter, err := newExporter(context.Background(), config, &output)
+	assert.NoError(t, err)
+
+	provider := NewProvider(config, exporter)
+	_, span := provider.Tracer(TracerName).Start(context.Background(), "ArticleRepository.CreateArticle")
+	span.End()
+	assert.NoError(t, provider.Shutdown(context.Background()))
+
+	var exported map[string]interface{}
+	assert.NoError(t, json.Unmarshal(output.Bytes(), &exported))
+	assert.Equal(t, "ArticleRepository.CreateArticle", exported["Name"])
+}
+
+func TestNewExporterInvalid(t *testing.T) {
+	exporter, err := newExporter(context.Background(), Config{Exporter: "none"}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, exporter)
+
+	_, err = newExporter(context.Background(), Config{Exporter: "zipkin"}, nil)
+	assert.Error(t, err)
+}
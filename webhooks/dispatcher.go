@@ -0,0 +1,446 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/internal/randomid"
+)
+
+const userAgent = "restful-api-webhooks/1"
+
+type Config struct {
+	// Workers is the number of deliveries sent concurrently.
+	Workers int
+	// QueueSize bounds the deliveries waiting for a worker. When the queue is
+	// full new deliveries go straight to the dead letters.
+	QueueSize int
+	// Timeout bounds a single delivery attempt.
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts before a delivery is dead
+	// lettered. Retries wait InitialBackoff, doubled after every failure up
+	// to MaxBackoff.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// DeliveryLogSize and DeadLetterSize bound the delivery log and the dead
+	// letter list; the oldest entries are dropped first.
+	DeliveryLogSize int
+	DeadLetterSize  int
+	// AllowPrivateTargets lets subscriptions deliver to loopback, private and
+	// link-local addresses. It is meant for receivers on the same network.
+	AllowPrivateTargets bool
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Workers:         4,
+		QueueSize:       1000,
+		Timeout:         10 * time.Second,
+		MaxAttempts:     5,
+		InitialBackoff:  time.Second,
+		MaxBackoff:      time.Minute,
+		DeliveryLogSize: 1000,
+		DeadLetterSize:  1000,
+	}
+}
+
+type Option func(*Dispatcher)
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(d *Dispatcher) {
+		d.logger = logger
+	}
+}
+
+// WithHTTPClient replaces the client deliveries are sent with, including its
+// private address guard and redirect policy.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+type job struct {
+	subscription Subscription
+	eventID      string
+	event        string
+	payload      []byte
+	attempt      int
+}
+
+// Dispatcher holds the webhook subscriptions and delivers events to them in
+// the background. Subscriptions, the delivery log and the dead letters are
+// kept in memory.
+type Dispatcher struct {
+	config Config
+	client *http.Client
+	logger *slog.Logger
+
+	mu            sync.Mutex
+	subscriptions map[string]Subscription
+	deliveries    []Delivery
+	deadLetters   []DeadLetter
+	// retries holds the deliveries waiting for their backoff to pass.
+	retries map[*time.Timer]job
+	closed  bool
+
+	queue  chan job
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher starts the delivery workers. Close must be called to stop them.
+func NewDispatcher(config Config, opts ...Option) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		config:        config,
+		client:        newHTTPClient(config.AllowPrivateTargets),
+		logger:        slog.Default(),
+		subscriptions: map[string]Subscription{},
+		retries:       map[*time.Timer]job{},
+		queue:         make(chan job, config.QueueSize),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for j := range d.queue {
+				d.deliver(j)
+			}
+		}()
+	}
+	return d
+}
+
+// Subscribe adds a subscription for events sent to rawURL. An empty secret is
+// replaced with a random one; the returned subscription carries it. A URL
+// with a private IP address is rejected unless AllowPrivateTargets is set;
+// host names are checked when each delivery connects.
+func (d *Dispatcher) Subscribe(rawURL string, eventTypes []string, secret string) (Subscription, error) {
+	subscription, err := newSubscription(rawURL, eventTypes, secret, d.config.AllowPrivateTargets, time.Now().UTC())
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.subscriptions[subscription.ID] = subscription
+	return subscription, nil
+}
+
+// Unsubscribe removes a subscription. Deliveries already queued for it are
+// dropped.
+func (d *Dispatcher) Unsubscribe(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, exists := d.subscriptions[id]; !exists {
+		return ErrNotFound
+	}
+	delete(d.subscriptions, id)
+	return nil
+}
+
+func (d *Dispatcher) Subscription(id string) (Subscription, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	subscription, exists := d.subscriptions[id]
+	if !exists {
+		return Subscription{}, ErrNotFound
+	}
+	return subscription, nil
+}
+
+// Subscriptions returns every subscription, oldest first.
+func (d *Dispatcher) Subscriptions() []Subscription {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	subscriptions := make([]Subscription, 0, len(d.subscriptions))
+	for _, subscription := range d.subscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if !subscriptions[i].CreatedAt.Equal(subscriptions[j].CreatedAt) {
+			return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+		}
+		return subscriptions[i].ID < subscriptions[j].ID
+	})
+	return subscriptions
+}
+
+// Deliveries returns the logged attempts for a subscription, newest first.
+func (d *Dispatcher) Deliveries(subscriptionID string) []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	deliveries := []Delivery{}
+	for i := len(d.deliveries) - 1; i >= 0; i-- {
+		if d.deliveries[i].SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, d.deliveries[i])
+		}
+	}
+	return deliveries
+}
+
+// DeadLetters returns the failed deliveries, newest first.
+func (d *Dispatcher) DeadLetters() []DeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	deadLetters := make([]DeadLetter, 0, len(d.deadLetters))
+	for i := len(d.deadLetters) - 1; i >= 0; i-- {
+		deadLetters = append(deadLetters, d.deadLetters[i])
+	}
+	return deadLetters
+}
+
+// RetryDeadLetter removes a dead letter and queues its event again, starting
+// over with the first attempt. The subscription must still exist.
+func (d *Dispatcher) RetryDeadLetter(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, deadLetter := range d.deadLetters {
+		if deadLetter.ID != id {
+			continue
+		}
+		subscription, exists := d.subscriptions[deadLetter.SubscriptionID]
+		if !exists {
+			return fmt.Errorf("subscription %s: %w", deadLetter.SubscriptionID, ErrNotFound)
+		}
+		d.deadLetters = append(d.deadLetters[:i], d.deadLetters[i+1:]...)
+		d.enqueueLocked(job{
+			subscription: subscription,
+			eventID:      deadLetter.EventID,
+			event:        deadLetter.Event,
+			payload:      deadLetter.Payload,
+			attempt:      1,
+		})
+		return nil
+	}
+	return ErrNotFound
+}
+
+// Publish queues event for every subscription that asked for it. It never
+// blocks on delivery.
+func (d *Dispatcher) Publish(event string, data any) {
+	e := Event{
+		ID:        randomid.Hex(16),
+		Type:      event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		d.logger.Error("Failed to encode webhook event", "event", event, "error", err)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, subscription := range d.subscriptions {
+		if subscription.wants(event) {
+			d.enqueueLocked(job{subscription: subscription, eventID: e.ID, event: event, payload: payload, attempt: 1})
+		}
+	}
+}
+
+// HandleEvent publishes a repository event, with the article as its data. It
+// is subscribed to the event bus.
+func (d *Dispatcher) HandleEvent(ctx context.Context, event events.Event) {
+	d.Publish(event.Type(), event.Subject())
+}
+
+func (d *Dispatcher) enqueueLocked(j job) {
+	if d.closed {
+		d.deadLetterLocked(j, "dispatcher shut down")
+		return
+	}
+	select {
+	case d.queue <- j:
+	default:
+		d.deadLetterLocked(j, "delivery queue full")
+	}
+}
+
+// backoff returns the wait before the given retry, counting from 1.
+func (d *Dispatcher) backoff(retry int) time.Duration {
+	delay := d.config.InitialBackoff
+	for i := 1; i < retry && delay < d.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.config.MaxBackoff)
+}
+
+func (d *Dispatcher) deliver(j job) {
+	d.mu.Lock()
+	_, exists := d.subscriptions[j.subscription.ID]
+	d.mu.Unlock()
+	if !exists {
+		return
+	}
+
+	start := time.Now()
+	status, err := d.send(j)
+	delivery := Delivery{
+		EventID:        j.eventID,
+		SubscriptionID: j.subscription.ID,
+		Event:          j.event,
+		Attempt:        j.attempt,
+		StatusCode:     status,
+		Succeeded:      err == nil,
+		DurationMs:     float64(time.Since(start).Microseconds()) / 1000,
+		Time:           start.UTC(),
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deliveries = appendBounded(d.deliveries, delivery, d.config.DeliveryLogSize)
+	if err == nil {
+		return
+	}
+	if !retryable(status) || j.attempt >= d.config.MaxAttempts {
+		d.deadLetterLocked(j, err.Error())
+		return
+	}
+	if d.closed {
+		d.deadLetterLocked(j, "dispatcher shut down before retry: "+err.Error())
+		return
+	}
+
+	delay := d.backoff(j.attempt)
+	j.attempt++
+	// The timer is registered under the lock, which its callback also takes,
+	// so the callback always finds it in retries.
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if _, pending := d.retries[timer]; pending {
+			delete(d.retries, timer)
+			d.enqueueLocked(j)
+		}
+	})
+	d.retries[timer] = j
+}
+
+func (d *Dispatcher) send(j job) (int, error) {
+	ctx, cancel := context.WithTimeout(d.ctx, d.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.subscription.URL, bytes.NewReader(j.payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, j.event)
+	req.Header.Set(EventIDHeader, j.eventID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(j.subscription.Secret, timestamp, j.payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether a failed attempt is worth repeating. Network
+// errors, timeouts, rate limiting and server errors are; other client errors
+// mean the receiver rejected the event and it goes to the dead letters.
+func retryable(status int) bool {
+	return status == 0 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500
+}
+
+func (d *Dispatcher) deadLetterLocked(j job, reason string) {
+	d.deadLetters = appendBounded(d.deadLetters, DeadLetter{
+		ID:             randomid.Hex(8),
+		EventID:        j.eventID,
+		SubscriptionID: j.subscription.ID,
+		URL:            j.subscription.URL,
+		Event:          j.event,
+		Payload:        j.payload,
+		Attempts:       j.attempt,
+		Error:          reason,
+		FailedAt:       time.Now().UTC(),
+	}, d.config.DeadLetterSize)
+	d.logger.Warn("Webhook delivery failed",
+		"subscription", j.subscription.ID,
+		"event", j.event,
+		"eventId", j.eventID,
+		"attempts", j.attempt,
+		"error", reason,
+	)
+}
+
+func appendBounded[T any](items []T, item T, limit int) []T {
+	items = append(items, item)
+	if len(items) > limit {
+		items = append(items[:0], items[len(items)-limit:]...)
+	}
+	return items
+}
+
+// Close stops accepting events, dead letters the deliveries waiting for a
+// retry and waits for the queued ones to be attempted once more. If ctx ends
+// first, the attempts in flight are cancelled.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	for timer, j := range d.retries {
+		// A timer that already fired is left to its callback, which finds the
+		// dispatcher closed and dead letters the job itself.
+		if timer.Stop() {
+			delete(d.retries, timer)
+			d.deadLetterLocked(j, "dispatcher shut down before retry")
+		}
+	}
+	close(d.queue)
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return errors.Join(errors.New("webhook deliveries cancelled"), ctx.Err())
+	}
+}
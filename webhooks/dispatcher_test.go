@@ -0,0 +1,288 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver is a local webhook endpoint that answers with the queued status
+// codes in turn, then with 200.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+func newReceiver(t *testing.T, statuses ...int) (*receiver, *httptest.Server) {
+	r := &receiver{statuses: statuses}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		r.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return r, server
+}
+
+func (r *receiver) received() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedRequest(nil), r.requests...)
+}
+
+func testConfig() Config {
+	config := DefaultConfig()
+	config.Timeout = time.Second
+	config.MaxAttempts = 3
+	config.InitialBackoff = time.Millisecond
+	config.MaxBackoff = 4 * time.Millisecond
+	// The receivers in these tests listen on loopback.
+	config.AllowPrivateTargets = true
+	return config
+}
+
+func newTestDispatcher(t *testing.T, config Config) *Dispatcher {
+	d := NewDispatcher(config)
+	t.Cleanup(func() { d.Close(context.Background()) })
+	return d
+}
+
+func TestDispatcherDeliversSignedEvents(t *testing.T) {
+	r, server := newReceiver(t)
+	d := newTestDispatcher(t, testConfig())
+
+	subscription, err := d.Subscribe(server.URL+"/hook", []string{EventArticleCreated}, "0123456789abcdef")
+	assert.NoError(t, err)
+
+	d.Publish(EventArticleUpdated, map[string]int{"id": 1})
+	d.Publish(EventArticleCreated, map[string]int{"id": 2})
+
+	assert.Eventually(t, func() bool { return len(d.Deliveries(subscription.ID)) == 1 }, time.Second, time.Millisecond)
+	requests := r.received()
+	assert.Len(t, requests, 1)
+
+	header := requests[0].header
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	assert.NoError(t, err)
+	assert.True(t, Verify("0123456789abcdef", timestamp, requests[0].body, header.Get(SignatureHeader)))
+	assert.False(t, Verify("another-secret-value", timestamp, requests[0].body, header.Get(SignatureHeader)))
+	assert.Equal(t, EventArticleCreated, header.Get(EventHeader))
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+
+	var event Event
+	assert.NoError(t, json.Unmarshal(requests[0].body, &event))
+	assert.Equal(t, EventArticleCreated, event.Type)
+	assert.Equal(t, header.Get(EventIDHeader), event.ID)
+	assert.Equal(t, map[string]any{"id": float64(2)}, event.Data)
+
+	delivery := d.Deliveries(subscription.ID)[0]
+	assert.True(t, delivery.Succeeded)
+	assert.Equal(t, http.StatusOK, delivery.StatusCode)
+	assert.Equal(t, 1, delivery.Attempt)
+}
+
+func TestDispatcherRetriesWithBackoff(t *testing.T) {
+	r, server := newReceiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	d := newTestDispatcher(t, testConfig())
+	subscription, _ := d.Subscribe(server.URL, []string{EventArticleCreated}, "")
+
+	d.Publish(EventArticleCreated, nil)
+
+	assert.Eventually(t, func() bool { return len(d.Deliveries(subscription.ID)) == 3 }, time.Second, time.Millisecond)
+	deliveries := d.Deliveries(subscription.ID)
+	assert.True(t, deliveries[0].Succeeded)
+	assert.Equal(t, 3, deliveries[0].Attempt)
+	assert.Equal(t, http.StatusTooManyRequests, deliveries[1].StatusCode)
+	assert.Equal(t, "receiver answered 503", deliveries[2].Error)
+	assert.Empty(t, d.DeadLetters())
+
+	requests := r.received()
+	assert.Equal(t, requests[0].header.Get(EventIDHeader), requests[2].header.Get(EventIDHeader))
+}
+
+func TestDispatcherDeadLetters(t *testing.T) {
+	_, server := newReceiver(t, 500, 500, 500, http.StatusGone)
+	d := newTestDispatcher(t, testConfig())
+	subscription, _ := d.Subscribe(server.URL, []string{EventArticleCreated}, "")
+
+	d.Publish(EventArticleCreated, map[string]int{"id": 1})
+	assert.Eventually(t, func() bool { return len(d.DeadLetters()) == 1 }, time.Second, time.Millisecond)
+
+	deadLetter := d.DeadLetters()[0]
+	assert.Equal(t, 3, deadLetter.Attempts)
+	assert.Equal(t, "receiver answered 500", deadLetter.Error)
+	assert.Equal(t, subscription.ID, deadLetter.SubscriptionID)
+	assert.Contains(t, string(deadLetter.Payload), `"data":{"id":1}`)
+
+	// A rejection is not retried.
+	assert.NoError(t, d.RetryDeadLetter(deadLetter.ID))
+	assert.Eventually(t, func() bool { return len(d.DeadLetters()) == 1 && d.DeadLetters()[0].ID != deadLetter.ID }, time.Second, time.Millisecond)
+	assert.Equal(t, 1, d.DeadLetters()[0].Attempts)
+	assert.Equal(t, "receiver answered 410", d.DeadLetters()[0].Error)
+
+	// The third try succeeds.
+	assert.NoError(t, d.RetryDeadLetter(d.DeadLetters()[0].ID))
+	assert.Eventually(t, func() bool { return len(d.DeadLetters()) == 0 && d.Deliveries(subscription.ID)[0].Succeeded }, time.Second, time.Millisecond)
+	assert.ErrorIs(t, d.RetryDeadLetter(deadLetter.ID), ErrNotFound)
+}
+
+func TestDispatcherRefusesPrivateTargets(t *testing.T) {
+	r, server := newReceiver(t)
+	config := testConfig()
+	config.AllowPrivateTargets = false
+	d := newTestDispatcher(t, config)
+
+	for _, target := range []string{server.URL, "http://10.0.0.1/hook", "http://[::1]/hook", "http://169.254.169.254/latest"} {
+		_, err := d.Subscribe(target, []string{EventArticleCreated}, "")
+		assert.ErrorContains(t, err, "must not point to a private address", target)
+	}
+
+	// A host name is resolved when the delivery connects.
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	subscription, err := d.Subscribe("http://localhost:"+port+"/hook", []string{EventArticleCreated}, "")
+	assert.NoError(t, err)
+
+	d.Publish(EventArticleCreated, nil)
+	assert.Eventually(t, func() bool { return len(d.DeadLetters()) == 1 }, time.Second, time.Millisecond)
+	assert.Contains(t, d.DeadLetters()[0].Error, "is a private address")
+	assert.False(t, d.Deliveries(subscription.ID)[0].Succeeded)
+	assert.Empty(t, r.received())
+}
+
+func TestDispatcherDoesNotFollowRedirects(t *testing.T) {
+	r, target := newReceiver(t)
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	t.Cleanup(redirect.Close)
+	d := newTestDispatcher(t, testConfig())
+	d.Subscribe(redirect.URL, []string{EventArticleCreated}, "")
+
+	d.Publish(EventArticleCreated, nil)
+	assert.Eventually(t, func() bool { return len(d.DeadLetters()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, "receiver answered 302", d.DeadLetters()[0].Error)
+	assert.Empty(t, r.received())
+}
+
+func TestPrivateAddr(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1", "224.0.0.1"} {
+		assert.True(t, privateAddr(netip.MustParseAddr(addr)), addr)
+	}
+	for _, addr := range []string{"8.8.8.8", "93.184.216.34", "2606:4700::1111"} {
+		assert.False(t, privateAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestDispatcherQueueFull(t *testing.T) {
+	config := testConfig()
+	config.Workers = 0
+	config.QueueSize = 1
+	d := newTestDispatcher(t, config)
+	d.Subscribe("http://127.0.0.1:1/hook", []string{EventArticleCreated}, "")
+
+	d.Publish(EventArticleCreated, nil)
+	d.Publish(EventArticleCreated, nil)
+
+	assert.Len(t, d.DeadLetters(), 1)
+	assert.Equal(t, "delivery queue full", d.DeadLetters()[0].Error)
+}
+
+func TestDispatcherCloseDeadLettersPendingRetries(t *testing.T) {
+	_, server := newReceiver(t, http.StatusBadGateway)
+	config := testConfig()
+	config.InitialBackoff = time.Hour
+	config.MaxBackoff = time.Hour
+	d := NewDispatcher(config)
+	subscription, _ := d.Subscribe(server.URL, []string{EventArticleCreated}, "")
+
+	d.Publish(EventArticleCreated, nil)
+	assert.Eventually(t, func() bool { return len(d.Deliveries(subscription.ID)) == 1 }, time.Second, time.Millisecond)
+
+	assert.NoError(t, d.Close(context.Background()))
+	assert.Len(t, d.DeadLetters(), 1)
+	assert.Equal(t, "dispatcher shut down before retry", d.DeadLetters()[0].Error)
+
+	d.Publish(EventArticleCreated, nil)
+	assert.Equal(t, "dispatcher shut down", d.DeadLetters()[0].Error)
+	assert.NoError(t, d.Close(context.Background()))
+}
+
+func TestDispatcherSubscriptions(t *testing.T) {
+	d := newTestDispatcher(t, testConfig())
+
+	_, err := d.Subscribe("ftp://example.com", []string{EventArticleCreated}, "")
+	assert.ErrorIs(t, err, ErrInvalidSubscription)
+	_, err = d.Subscribe("/relative", []string{EventArticleCreated}, "")
+	assert.ErrorIs(t, err, ErrInvalidSubscription)
+	_, err = d.Subscribe("https://example.com", nil, "")
+	assert.ErrorContains(t, err, "events must not be empty")
+	_, err = d.Subscribe("https://example.com", []string{"article.published"}, "")
+	assert.ErrorContains(t, err, `unknown event "article.published"`)
+	_, err = d.Subscribe("https://example.com", []string{EventArticleCreated}, "short")
+	assert.ErrorContains(t, err, "secret must be at least 16 characters")
+
+	first, err := d.Subscribe("https://example.com/a", []string{EventArticleCreated, EventArticleCreated}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{EventArticleCreated}, first.Events)
+	assert.Len(t, first.Secret, 64)
+	second, _ := d.Subscribe("https://example.com/b", Events, "")
+
+	assert.Equal(t, []Subscription{first, second}, d.Subscriptions())
+	got, err := d.Subscription(second.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, second, got)
+
+	assert.NoError(t, d.Unsubscribe(first.ID))
+	assert.ErrorIs(t, d.Unsubscribe(first.ID), ErrNotFound)
+	_, err = d.Subscription(first.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, []Subscription{second}, d.Subscriptions())
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{config: Config{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second}}
+	assert.Equal(t, time.Second, d.backoff(1))
+	assert.Equal(t, 2*time.Second, d.backoff(2))
+	assert.Equal(t, 8*time.Second, d.backoff(4))
+	assert.Equal(t, 10*time.Second, d.backoff(5))
+	assert.Equal(t, 10*time.Second, d.backoff(60))
+}
+
+func TestDispatcherHandleEvent(t *testing.T) {
+	r, server := newReceiver(t)
+	d := newTestDispatcher(t, testConfig())
+	subscription, _ := d.Subscribe(server.URL, []string{EventArticleUpdated}, "")
+
+	d.HandleEvent(context.Background(), events.ArticleUpdated{
+		Article:  models.Article{ID: 1, Title: "New", Content: "Body"},
+		Previous: models.Article{ID: 1, Title: "Old", Content: "Body"},
+	})
+
+	assert.Eventually(t, func() bool { return len(d.Deliveries(subscription.ID)) == 1 }, time.Second, time.Millisecond)
+	var event Event
+	assert.NoError(t, json.Unmarshal(r.received()[0].body, &event))
+	assert.Equal(t, EventArticleUpdated, event.Type)
+	assert.Equal(t, map[string]any{"id": float64(1), "title": "New", "content": "Body"}, event.Data)
+}
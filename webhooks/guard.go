@@ -0,0 +1,59 @@
+package webhooks
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range, which like the private
+// ranges is not reachable from the internet.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// privateAddr reports whether addr is loopback, private, link-local,
+// unspecified or multicast, the addresses a receiver on the internet never
+// has but services next to this one do.
+func privateAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsUnspecified() || addr.IsMulticast() || sharedAddressSpace.Contains(addr)
+}
+
+// guardDial refuses connections to private addresses. It runs after the host
+// name has been resolved, for every address tried, so a name that resolves,
+// or later re-resolves, to an internal address is caught too.
+func guardDial(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("webhook target %s: %w", address, err)
+	}
+	if privateAddr(addrPort.Addr()) {
+		return fmt.Errorf("webhook target %s is a private address", addrPort.Addr())
+	}
+	return nil
+}
+
+// newHTTPClient returns the client deliveries are sent with. Redirects are
+// not followed, so a receiver cannot bounce deliveries to another host, and
+// unless allowPrivate is set connections to private addresses are refused.
+// Deliveries go out directly, without the proxy from the environment, so the
+// guard sees the receiver's address.
+func newHTTPClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = guardDial
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
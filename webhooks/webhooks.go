@@ -0,0 +1,147 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/brothergiez/restful-api/events"
+	"github.com/brothergiez/restful-api/internal/randomid"
+)
+
+// Event types sent to subscribers.
+const (
+	EventArticleCreated = events.TypeArticleCreated
+	EventArticleUpdated = events.TypeArticleUpdated
+	EventArticleDeleted = events.TypeArticleDeleted
+)
+
+// Events lists every event type a subscription can ask for.
+var Events = []string{EventArticleCreated, EventArticleUpdated, EventArticleDeleted}
+
+// Headers set on every delivery. The event ID is the same on every attempt,
+// so receivers can use it to drop duplicates.
+const (
+	EventHeader     = "X-Webhook-Event"
+	EventIDHeader   = "X-Webhook-ID"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+// minSecretLength matches the minimum length of API keys.
+const minSecretLength = 16
+
+var (
+	// ErrNotFound is returned for an unknown subscription or dead letter ID.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidSubscription wraps every validation failure of Subscribe.
+	ErrInvalidSubscription = errors.New("invalid webhook subscription")
+)
+
+type Subscription struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret signs the deliveries. It is only shown when the subscription is
+	// created.
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (s Subscription) wants(event string) bool {
+	return slices.Contains(s.Events, event)
+}
+
+// Event is the JSON body of a delivery.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+// Delivery is one attempt to deliver an event, kept in the delivery log.
+type Delivery struct {
+	EventID        string    `json:"eventId"`
+	SubscriptionID string    `json:"subscriptionId"`
+	Event          string    `json:"event"`
+	Attempt        int       `json:"attempt"`
+	StatusCode     int       `json:"statusCode,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	Succeeded      bool      `json:"succeeded"`
+	DurationMs     float64   `json:"durationMs"`
+	Time           time.Time `json:"time"`
+}
+
+// DeadLetter is an event that could not be delivered to a subscription,
+// either because every attempt failed or because the receiver rejected it.
+type DeadLetter struct {
+	ID             string          `json:"id"`
+	EventID        string          `json:"eventId"`
+	SubscriptionID string          `json:"subscriptionId"`
+	URL            string          `json:"url"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Attempts       int             `json:"attempts"`
+	Error          string          `json:"error"`
+	FailedAt       time.Time       `json:"failedAt"`
+}
+
+// Sign returns the signature header value for a body sent at timestamp: the
+// hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the subscription secret.
+// The timestamp is signed too, so a captured delivery cannot be sent again
+// later under a fresh timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature was produced by Sign with the same secret,
+// timestamp and body. Receivers should also reject old timestamps.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+func newSubscription(rawURL string, eventTypes []string, secret string, allowPrivate bool, now time.Time) (Subscription, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Subscription{}, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidSubscription)
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil && !allowPrivate && privateAddr(addr) {
+		return Subscription{}, fmt.Errorf("%w: url must not point to a private address", ErrInvalidSubscription)
+	}
+	if len(eventTypes) == 0 {
+		return Subscription{}, fmt.Errorf("%w: events must not be empty", ErrInvalidSubscription)
+	}
+	var wanted []string
+	for _, event := range eventTypes {
+		if !slices.Contains(Events, event) {
+			return Subscription{}, fmt.Errorf("%w: unknown event %q", ErrInvalidSubscription, event)
+		}
+		if !slices.Contains(wanted, event) {
+			wanted = append(wanted, event)
+		}
+	}
+	if secret == "" {
+		secret = randomid.Hex(32)
+	} else if len(secret) < minSecretLength {
+		return Subscription{}, fmt.Errorf("%w: secret must be at least %d characters", ErrInvalidSubscription, minSecretLength)
+	}
+
+	return Subscription{
+		ID:        randomid.Hex(8),
+		URL:       u.String(),
+		Events:    wanted,
+		Secret:    secret,
+		CreatedAt: now,
+	}, nil
+}